@@ -34,7 +34,7 @@ value is very fast.
 
 • Lookup algortithm complexity is O(log(n)), tree depth is log(n).
 
-• The package provide facility to use string, uint64 and network as key.
+• The package provide facility to use string, uint64, time and IPv4 or IPv6 network as key.
 
 Benchmark
 
@@ -97,7 +97,8 @@ colected.
  var memory_free uint32
 
 memory_pool growth by block of 64k nodes. Each time there are no free node, memory pool
-has one more slot of 64k node.
+has one more slot of 64k node. The first blocks are smaller (64, 256 and 4096 nodes) so
+a small tree does not allocate 64k nodes, NewTreeCapacity could set the first block size.
 
 memory_backref is dichotomic sorted list of pointer start and stop which reference the
 memory_pool index which contains pointer. ptr_start = &[0]node, ptr_stop = &[65535]node.
@@ -138,5 +139,16 @@ I use a property which is &Node adress = &Node.node adress. I manipulate only no
 each data. If I need to insert a leaf, I use Node.node. When I browse the tree I need to
 kwnown if I encounter leaf or node. I just chack the msb of the reference.
 
+4. Saving memory : typed data
+
+The tree is generic, Tree[V] stores the data of type V directly in the leaf Leaf[V], so
+there are no type assertion and no boxed value for each leaf. Radix is Tree[any] and Node
+is Leaf[any], they are kept for compatibility.
+
+ type Leaf[V any] struct {
+    node node
+    Data V
+ }
+
 */
 package radix
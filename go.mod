@@ -1,3 +1,3 @@
 module github.com/thierry-f-78/go-radix
 
-go 1.14
+go 1.23
@@ -3,12 +3,9 @@
 package radix
 
 import "bytes"
-import "encoding/hex"
 import "fmt"
 import "io"
-import "net"
 import "os"
-import "reflect"
 import "strings"
 import "unsafe"
 
@@ -23,44 +20,95 @@ type node struct {
 	/* 32 */
 }
 
-// Node is a struct which describe leaf of the tree.
-type Node struct {
+// Leaf is a struct which describe leaf of the tree. The data
+// associated with the leaf is stored in the leaf without boxing.
+type Leaf[V any] struct {
 	/* 32 */ node node // It is absolutely necessary this member was the first
-	/* 16 */ Data interface{} // Contains interface matching the node
-	/* 48 */
+	/*  ? */ Data V // Contains data matching the node
 }
 
-func n2N(n *node)(*Node) {
-	return reflect.NewAt(reflect.TypeOf(Node{}), unsafe.Pointer(n)).Interface().(*Node)
+// Node is a struct which describe leaf of the tree. This is
+// the untyped leaf used by Radix.
+type Node = Leaf[any]
+
+func n2N[V any](n *node)(*Leaf[V]) {
+	return (*Leaf[V])(unsafe.Pointer(n))
 }
 
 const null = uint32(0x00000000)
 const node_sz = uint32(unsafe.Sizeof(node{}))
-const leaf_sz = uint32(unsafe.Sizeof(Node{}))
 
-// Radix is the struct which contains the tree root.
-type Radix struct {
+/* leaf size depends on the data type */
+func leaf_size[V any]()(uint32) {
+	var l Leaf[V]
+
+	return uint32(unsafe.Sizeof(l))
+}
+
+// Tree is the struct which contains the tree root. The leaf
+// data is typed with V.
+type Tree[V any] struct {
 	Node uint32
 	length int
 	node node_pool
-	leaf leaf_pool
+	leaf leaf_pool[V]
 	ptr_range []ptr_range
+	codec Codec[V]
+	counted bool
+	cow *cow_state[V]
 }
 
+// Radix is the struct which contains the tree root. This is the
+// untyped tree, the data of each leaf is an interface{}.
+type Radix = Tree[any]
+
 // NewRadix return initialized *Radix tree.
 func NewRadix()(*Radix) {
-	var radix *Radix
+	return NewTree[any]()
+}
+
+// NewTree return initialized *Tree tree.
+func NewTree[V any]()(*Tree[V]) {
+	var radix *Tree[V]
 
-	radix = &Radix{}
+	radix = &Tree[V]{}
 	radix.length = 0
 
 	return radix
 }
 
-func display_node(fh io.Writer, r *Radix, n *node, ref uint32, level int, branch string) {
+// NewRadixCapacity return initialized *Radix tree. capacity is the
+// expected number of leaves, it is used to size the first memory chunk.
+func NewRadixCapacity(capacity int)(*Radix) {
+	return NewTreeCapacity[any](capacity)
+}
+
+// NewTreeCapacity return initialized *Tree tree. capacity is the expected
+// number of leaves, it is used to size the first memory chunk. The next
+// chunks grow up to 65536 entries.
+func NewTreeCapacity[V any](capacity int)(*Tree[V]) {
+	var radix *Tree[V]
+
+	radix = NewTree[V]()
+	radix.leaf.first = capacity
+	if radix.leaf.first < 2 {
+		radix.leaf.first = 2
+	}
+	if radix.leaf.first > chunk_max {
+		radix.leaf.first = chunk_max
+	}
+
+	/* nodes need one more entry because the first one is reserved */
+	radix.node.first = radix.leaf.first + 1
+	if radix.node.first > chunk_max {
+		radix.node.first = chunk_max
+	}
+
+	return radix
+}
+
+func display_node[V any](fh io.Writer, r *Tree[V], n *node, ref uint32, level int, branch string) {
 	var typ string
-	var ip net.IPNet
-	var b []byte
 	var indent string
 	var key string
 
@@ -71,24 +119,12 @@ func display_node(fh io.Writer, r *Radix, n *node, ref uint32, level int, branch
 	}
 
 	indent = strings.Repeat("   ", level)
-
-	if len(n.Bytes) <= 4 && n.End <= 32 {
-		b = make([]byte, len(n.Bytes))
-		copy(b, []byte(n.Bytes))
-		for len(b) < 4 {
-			b = append([]byte{0x00}, b...)
-		}
-		ip.IP = net.IP(b)
-		ip.Mask = net.CIDRMask(int(n.End) + 1, 32)
-		key = ip.String()
-	} else {
-		key = hex.EncodeToString([]byte(n.Bytes))
-	}
+	key = format_auto([]byte(n.Bytes), n.End + 1)
 
 	fmt.Fprintf(fh, "%s%s: %p(%08x)/%s start=%d end=%d key=%s\n", indent, branch, n, ref, typ, n.Start, n.End, key)
 }
 
-func browse_node(fh io.Writer, r *Radix, n *node, ref uint32, level int, branch string) {
+func browse_node[V any](fh io.Writer, r *Tree[V], n *node, ref uint32, level int, branch string) {
 	display_node(fh, r, n, ref, level, branch)
 	if n.Left != null {
 		browse_node(fh, r, r.r2n(n.Left), n.Left, level+1, "L")
@@ -98,7 +134,7 @@ func browse_node(fh io.Writer, r *Radix, n *node, ref uint32, level int, branch
 	}
 }
 
-func (r *Radix)Debug(fh io.Writer) {
+func (r *Tree[V])Debug(fh io.Writer) {
 
 	if r.Node == null {
 		fmt.Fprintf(fh, "root pointer nil\n")
@@ -108,11 +144,11 @@ func (r *Radix)Debug(fh io.Writer) {
 	browse_node(fh, r, r.r2n(r.Node), r.Node, 0, "-")
 }
 
-func (r *Radix)DebugStdout() {
+func (r *Tree[V])DebugStdout() {
 	r.Debug(os.Stdout)
 }
 
-func (r *Radix)check_lvl1_and_die_on_error() {
+func (r *Tree[V])check_lvl1_and_die_on_error() {
 	var root *node
 
 	if r.Node == null {
@@ -128,7 +164,7 @@ func (r *Radix)check_lvl1_and_die_on_error() {
 }
 
 // Len return the number of leaf in the tree.
-func (r *Radix)Len()(int) {
+func (r *Tree[V])Len()(int) {
 	return r.length
 }
 
@@ -137,6 +173,7 @@ type Node_counters struct {
 	Capacity int `json:"capacity"` // the total nodes/leaf capacity
 	Free int `json:"free"` // the number of free nodes/leaf
 	Size int `json:"size"` // the size of a node/leaf in bytes
+	Reclaimed int `json:"reclaimed"` // the bytes released by Compact
 }
 
 // Node_counters describe tree counters
@@ -148,25 +185,27 @@ type Counters struct {
 
 // Counters return counters useful to monitor the radix tree
 // usage.
-func (r *Radix)Counters()(*Counters) {
+func (r *Tree[V])Counters()(*Counters) {
 	return &Counters{
 		Length: r.length,
 		Node: Node_counters{
 			Capacity: r.node.capacity,
 			Free: r.node.free,
 			Size: int(node_sz),
+			Reclaimed: r.node.reclaimed,
 		},
 		Leaf: Node_counters{
 			Capacity: r.leaf.capacity,
 			Free: r.leaf.free,
-			Size: int(leaf_sz),
+			Size: int(leaf_size[V]()),
+			Reclaimed: r.leaf.reclaimed,
 		},
 	}
 }
 
 // Equal return true if nodes are equal. Node are equal if there are the same
 // prefix length and bytes.
-func Equal(n1 *Node, n2 *Node)(bool) {
+func Equal[V any](n1 *Leaf[V], n2 *Leaf[V])(bool) {
 	return equal(&n1.node, &n2.node)
 }
 func equal(n1 *node, n2 *node)(bool) {
@@ -177,7 +216,7 @@ func equal(n1 *node, n2 *node)(bool) {
 }
 
 /* Print node value */
-func (r *Radix)get_string(n *node)(string) {
+func (r *Tree[V])get_string(n *node)(string) {
 	var out string
 	var b byte
 	var mode string
@@ -222,15 +261,15 @@ func (n *node)isAlignedChildrenOf(p *node)(bool) {
 
 // LookupLonguestPath take the radix tree and a key/length prefix, return the list
 // of all leaf matching the prefix. If none match, return nil
-func (r *Radix)LookupLonguestPath(data *[]byte, length int16)([]*Node) {
+func (r *Tree[V])LookupLonguestPath(data *[]byte, length int16)([]*Leaf[V]) {
 	var node *node
-	var path_node []*Node
+	var path_node []*Leaf[V]
 	var end int16
 	var ref uint32
 
 	/* Browse tree */
 	length-- /* convert length to index of last bit */
-	path_node = make([]*Node, 0)
+	path_node = make([]*Leaf[V], 0)
 	ref = r.Node
 	node = r.r2n(r.Node)
 	for {
@@ -250,7 +289,7 @@ func (r *Radix)LookupLonguestPath(data *[]byte, length int16)([]*Node) {
 			return path_node
 		}
 		if is_leaf(ref) {
-			path_node = append(path_node, n2N(node))
+			path_node = append(path_node, n2N[V](node))
 		}
 
 		/* If the node no match or we reach end of browsing, return data */
@@ -270,11 +309,24 @@ func (r *Radix)LookupLonguestPath(data *[]byte, length int16)([]*Node) {
 	}
 }
 
+// LookupShortest get a key/length prefix and return the leaf which match the
+// shortest part of the prefix, this is the least specific leaf covering the
+// prefix. Return nil if none match.
+func (r *Tree[V])LookupShortest(data *[]byte, length int16)(*Leaf[V]) {
+	var ref uint32
+
+	ref = r.covering_from(r.Node, *data, length)
+	if ref == null {
+		return nil
+	}
+	return n2N[V](r.r2n(ref))
+}
+
 // LookupLonguest get a key/length prefix and return the leaf which match the
 // longest part of the prefix. Return nil if none match.
-func (r *Radix)LookupLonguest(data *[]byte, length int16)(*Node) {
+func (r *Tree[V])LookupLonguest(data *[]byte, length int16)(*Leaf[V]) {
 	var node *node
-	var last_node *Node
+	var last_node *Leaf[V]
 	var end int16
 	var ref uint32
 
@@ -302,7 +354,7 @@ func (r *Radix)LookupLonguest(data *[]byte, length int16)(*Node) {
 		 * also add node if match_only is not required
 		 */
 		if is_leaf(ref) {
-			last_node = n2N(node)
+			last_node = n2N[V](node)
 		}
 
 		/* We reach the end */
@@ -326,9 +378,9 @@ func (r *Radix)LookupLonguest(data *[]byte, length int16)(*Node) {
 // closest value of the key. This is usefull for constant prefix keys
 // like keys derivated from uint64 or time. If the tree is empty or greater
 // value not exists, return nil
-func (r *Radix)LookupGe(data *[]byte, length int16)(*Node) {
+func (r *Tree[V])LookupGe(data *[]byte, length int16)(*Leaf[V]) {
 	var node_c *node
-	var n *Node
+	var n *Leaf[V]
 	var ref uint32
 
 	node_c, ref = lookup_longuest_last_node(r, *data, length)
@@ -356,9 +408,9 @@ func (r *Radix)LookupGe(data *[]byte, length int16)(*Node) {
 // closest value of the key. This is usefull for constant prefix keys
 // like keys derivated from uint64 or time. If the tree is empty or lesser
 // value not exists, return nil
-func (r *Radix)LookupLe(data *[]byte, length int16)(*Node) {
+func (r *Tree[V])LookupLe(data *[]byte, length int16)(*Leaf[V]) {
 	var node_c *node
-	var n *Node
+	var n *Leaf[V]
 	var ref uint32
 
 	node_c, ref = lookup_longuest_last_node(r, *data, length)
@@ -384,8 +436,8 @@ func (r *Radix)LookupLe(data *[]byte, length int16)(*Node) {
 
 // Get gets a key/length prefix and return exact match of the prefix. Exact match
 // is a node wich match the prefix bit and the length.
-func (r *Radix)Get(data *[]byte, length int16)(*Node) {
-	var n *Node
+func (r *Tree[V])Get(data *[]byte, length int16)(*Leaf[V]) {
+	var n *Leaf[V]
 	n = r.LookupLonguest(data, length)
 	if n == nil {
 		return nil
@@ -396,7 +448,7 @@ func (r *Radix)Get(data *[]byte, length int16)(*Node) {
 	return n
 }
 
-func lookup_longuest_last_node(r *Radix, data []byte, length int16)(*node, uint32) {
+func lookup_longuest_last_node[V any](r *Tree[V], data []byte, length int16)(*node, uint32) {
 	var node *node
 	var end int16
 	var ref uint32
@@ -439,7 +491,7 @@ func lookup_longuest_last_node(r *Radix, data []byte, length int16)(*node, uint3
 	}
 }
 
-func (r *Radix)replace(o *node, n *node) {
+func (r *Tree[V])replace(o *node, n *node) {
 	var replace_node *node
 
 	*n = *o
@@ -469,8 +521,22 @@ func (r *Radix)replace(o *node, n *node) {
 // Insert key/length prefix in the tree. The tree accept only unique value, if
 // the prefix already exists in the tree, return existing leaf,
 // otherwaise return nil.
-func (r *Radix)Insert(key *[]byte, length int16, data interface{})(*Node, bool) {
-	var leaf *Node
+func (r *Tree[V])Insert(key *[]byte, length int16, data V)(*Leaf[V], bool) {
+	var leaf *Leaf[V]
+	var ok bool
+
+	if r.cow != nil && length > 0 {
+		r.cow_insert(*key, length)
+	}
+	leaf, ok = r.insert(key, length, data)
+	if ok && r.counted {
+		r.count_fix(r.n2r(&leaf.node))
+	}
+	return leaf, ok
+}
+
+func (r *Tree[V])insert(key *[]byte, length int16, data V)(*Leaf[V], bool) {
+	var leaf *Leaf[V]
 	var lookup_node *node
 	var newnode *node
 	var bitno int16
@@ -518,9 +584,12 @@ func (r *Radix)Insert(key *[]byte, length int16, data interface{})(*Node, bool)
 		 */
 		if lookup_node.End == length - 1 {
 
-			/* Unique mode is active and the data is set, return stored data */
+			/* Unique mode is active and the data is set, return stored data.
+			 * The leaf allocated for the new entry is not used.
+			 */
 			if is_leaf(ref) {
-				return n2N(lookup_node), false
+				r.free(&leaf.node)
+				return n2N[V](lookup_node), false
 			}
 
 			/* replace original not leaf node by leaf Node, and
@@ -651,12 +720,25 @@ func (r *Radix)Insert(key *[]byte, length int16, data interface{})(*Node, bool)
 }
 
 // Delete remove Node from the tree.
-func (r *Radix)Delete(n *Node) {
-	r.del(&n.node)
+func (r *Tree[V])Delete(n *Leaf[V]) {
+	var ref uint32
+
+	if r.cow != nil {
+		ref = r.n2r(&n.node)
+		r.cow_delete(ref)
+		n = n2N[V](r.r2n(ref))
+	}
+	ref = r.del(&n.node)
 	r.length--
+	if r.counted {
+		r.count_fix(ref)
+	}
 }
 
-func (r *Radix)del(n *node) {
+/* Remove the node n. Return the lowest remaining node whose subtree
+ * lost a leaf, or null if there is none.
+ */
+func (r *Tree[V])del(n *node)(uint32) {
 	var p *node
 	var c *node
 	var ref uint32
@@ -670,8 +752,9 @@ func (r *Radix)del(n *node) {
 			p = r.node_alloc()
 			r.replace(n, p)
 			r.free(n)
+			return r.n2r(p)
 		}
-		return
+		return ref
 	}
 
 	/* If node has one child. Remove the node, and
@@ -689,15 +772,16 @@ func (r *Radix)del(n *node) {
 		if n.Parent == null {
 			r.Node = r.n2r(c)
 			r.free(n)
-			return
+			return null
 		}
+		ref = n.Parent
 		if r.r2n(n.Parent).Left == r.n2r(n) {
 			r.r2n(n.Parent).Left = r.n2r(c)
 		} else {
 			r.r2n(n.Parent).Right = r.n2r(c)
 		}
 		r.free(n)
-		return
+		return ref
 	}
 
 	/* If the node has no childs, just remove it. */
@@ -707,7 +791,7 @@ func (r *Radix)del(n *node) {
 		if n.Parent == null {
 			r.Node = null
 			r.free(n)
-			return
+			return null
 		}
 
 		/* Remove my branch on the parent node */
@@ -720,14 +804,16 @@ func (r *Radix)del(n *node) {
 
 		/* if the parent node is a leaf, do not remove */
 		if is_leaf(n.Parent) {
+			ref = n.Parent
 			r.free(n)
-			return
+			return ref
 		}
 
 		/* Remove the parent node */
 		r.free(n)
-		r.del(p)
+		return r.del(p)
 	}
+	return null
 }
 
 /*
@@ -742,11 +828,11 @@ func (r *Radix)del(n *node) {
 
 // Next return next Node in browsing order. Return nil
 // if we reach end of tree.
-func (r *Radix)Next(n *Node)(*Node) {
-	return r.next(&n.node)
+func (r *Tree[V])Next(n *Leaf[V])(*Leaf[V]) {
+	return r.next(r.fresh(&n.node))
 }
 
-func (r *Radix)next(n *node)(*Node) {
+func (r *Tree[V])next(n *node)(*Leaf[V]) {
 	var prev uint32
 	var ref uint32
 
@@ -793,7 +879,7 @@ func (r *Radix)next(n *node)(*Node) {
 
 		/* If we reach leaf, and I'n not com from parent, return node */
 		if is_leaf(ref) && prev == n.Parent {
-			return n2N(n)
+			return n2N[V](n)
 		}
 
 		/* Otherwise continue browsing */
@@ -812,11 +898,11 @@ func (r *Radix)next(n *node)(*Node) {
 
 // Next return next Node in browsing order. Return nil
 // if we reach end of tree.
-func (r *Radix)Prev(n *Node)(*Node) {
-	return r.prev(&n.node)
+func (r *Tree[V])Prev(n *Leaf[V])(*Leaf[V]) {
+	return r.prev(r.fresh(&n.node))
 }
 
-func (r *Radix)prev(n *node)(*Node) {
+func (r *Tree[V])prev(n *node)(*Leaf[V]) {
 	var prev_ref uint32
 	var ref uint32
 
@@ -863,7 +949,7 @@ func (r *Radix)prev(n *node)(*Node) {
 
 		/* If we reach leaf, and I'n not com from parent, return node */
 		if is_leaf(ref) && prev_ref == n.Parent {
-			return n2N(n)
+			return n2N[V](n)
 		}
 
 		/* Otherwise continue browsing */
@@ -872,14 +958,14 @@ func (r *Radix)prev(n *node)(*Node) {
 
 // First return first node of the tree. Return nil if the
 // tree is empty.
-func (r *Radix)First()(*Node) {
+func (r *Tree[V])First()(*Leaf[V]) {
 	if r.Node == null {
 		return nil
 	}
 
 	/* If entry node is a leaf, return it */
 	if is_leaf(r.Node) {
-		return n2N(r.r2n(r.Node))
+		return n2N[V](r.r2n(r.Node))
 	}
 
 	/* Otherwise return next node */
@@ -888,7 +974,7 @@ func (r *Radix)First()(*Node) {
 
 // Last return the last node of the tree. Return nil
 // if the tree is empty.
-func (r *Radix)Last()(*Node) {
+func (r *Tree[V])Last()(*Leaf[V]) {
 	var n *node
 
 	if r.Node == null {
@@ -906,27 +992,54 @@ func (r *Radix)Last()(*Node) {
 		} else if n.Left != null {
 			n = r.r2n(n.Left)
 		} else {
-			return n2N(n)
+			return n2N[V](n)
 		}
 	}
 }
 
-// Iter is a struct for managing iteration
-type Iter struct {
+// Iterator is a struct for managing iteration
+type Iterator[V any] struct {
 	node *node
 	next_node *node
 	key *[]byte
 	length int16
-	r *Radix
+	r *Tree[V]
+	reverse bool
 }
 
+// Iter is a struct for managing iteration on Radix
+type Iter = Iterator[any]
+
 // NewIter return struct Iter for browsing all nodes there children
 // match the given key/length prefix.
-func (r *Radix)NewIter(key *[]byte, length int16)(*Iter) {
-	var i *Iter
+func (r *Tree[V])NewIter(key *[]byte, length int16)(*Iterator[V]) {
+	var i *Iterator[V]
+
+	i = &Iterator[V]{}
+	i.init(r, key, length)
+	return i
+}
+
+/* Return the root of the subtree containing all the nodes matching
+ * the key/length prefix. Return null if none match.
+ */
+func (r *Tree[V])prefix_root(key []byte, length int16)(uint32) {
+	var n *node
+	var ref uint32
+
+	if length == 0 {
+		return r.Node
+	}
+	n, ref = lookup_longuest_last_node(r, key, length)
+	if n == nil || !is_children_of([]byte(n.Bytes), key, n.End, length - 1) {
+		return null
+	}
+	return ref
+}
+
+func (i *Iterator[V])init(r *Tree[V], key *[]byte, length int16)() {
 	var ref uint32
 
-	i = &Iter{}
 	i.key = key
 	i.length = length
 	i.r = r
@@ -934,43 +1047,95 @@ func (r *Radix)NewIter(key *[]byte, length int16)(*Iter) {
 	/* Lookup next node */
 	if length == 0 {
 		ref = r.Node
-		i.next_node = r.r2n(r.Node)
 	} else {
-		i.next_node, ref = lookup_longuest_last_node(r, *key, length)
-		if i.next_node != nil && !is_children_of([]byte(i.next_node.Bytes), *i.key, i.next_node.End, i.length - 1) {
-			i.next_node = nil
-		}
+		ref = r.prefix_root(*key, length)
 	}
+	i.next_node = r.r2n(ref)
 
 	/* No nodes found, next node is nil, abort iteration */
 	if i.next_node == nil {
-		return i
+		return
 	}
 
 	/* If the first node matching is a leaf, there is the entry point */
 	if is_leaf(ref) {
-		return i
+		return
 	}
 
 	/* Otherwise, lookup for next leaf */
 	i.set_next()
+}
+
+// NewReverseIter return struct Iter for browsing all nodes there children
+// match the given key/length prefix, from the last one to the first one.
+func (r *Tree[V])NewReverseIter(key *[]byte, length int16)(*Iterator[V]) {
+	var i *Iterator[V]
 
+	i = &Iterator[V]{}
+	i.init_reverse(r, key, length)
 	return i
 }
 
-func (i *Iter)set_next()() {
-	var n *Node
+func (i *Iterator[V])init_reverse(r *Tree[V], key *[]byte, length int16)() {
+	var root uint32
+
+	i.key = key
+	i.length = length
+	i.r = r
+	i.reverse = true
+
+	/* The last node of the matching subtree has no children, so
+	 * it is a leaf and there is the entry point.
+	 */
+	if length == 0 {
+		root = r.Node
+	} else {
+		root = r.prefix_root(*key, length)
+	}
+	if root != null {
+		i.next_node = r.r2n(r.last_node(root))
+	}
+}
+
+/* Return reverse iterator starting from leaf n and browsing all the
+ * tree. If n is nil, the iterator is empty.
+ */
+func (r *Tree[V])new_reverse_iter_from(n *Leaf[V])(*Iterator[V]) {
+	var i *Iterator[V]
+
+	i = &Iterator[V]{}
+	i.r = r
+	i.reverse = true
+	if n != nil {
+		i.next_node = &n.node
+	}
+	return i
+}
+
+func (i *Iterator[V])set_next()() {
+	var n *Leaf[V]
 
 	if i.next_node == nil {
 		return
 	}
-	n = i.r.next(i.next_node)
-	if n == nil {
-		i.next_node = nil
-		return
+	if i.reverse {
+		i.next_node = i.r.r2n(i.r.prev_leaf(i.r.n2r(i.next_node)))
 	} else {
-		i.next_node = &n.node
+		n = i.r.next(i.next_node)
+		if n == nil {
+			i.next_node = nil
+			return
+		} else {
+			i.next_node = &n.node
+		}
 	}
+	if i.next_node == nil {
+		return
+	}
+
+	/* The matching nodes are contiguous in tree order, so the
+	 * browsing stops on the first node which does not match.
+	 */
 	if i.length > 0 && !is_children_of([]byte(i.next_node.Bytes), *i.key, i.next_node.End, i.length - 1) {
 		i.next_node = nil
 	}
@@ -978,7 +1143,8 @@ func (i *Iter)set_next()() {
 
 // Next return true if there next node avalaible. This function
 // also perform lookup for the next node.
-func (i *Iter)Next()(bool) {
+func (i *Iterator[V])Next()(bool) {
+	i.next_node = i.r.fresh(i.next_node)
 	i.node = i.next_node
 	i.set_next()
 	return i.node != nil
@@ -986,6 +1152,33 @@ func (i *Iter)Next()(bool) {
 
 // Get return the node. Many calls on this function return  the same
 // value.
-func (i *Iter)Get()(*Node) {
-	return n2N(i.node)
+func (i *Iterator[V])Get()(*Leaf[V]) {
+	return n2N[V](i.node)
+}
+
+// Delete remove the current node from the tree. The iterator stay valid,
+// the next call to Next return the node following the removed one. Get
+// return nil until the next call to Next.
+func (i *Iterator[V])Delete()() {
+	if i.node == nil {
+		return
+	}
+	i.r.Delete(n2N[V](i.node))
+	i.node = nil
+}
+
+// RemoveIf remove all the leaves matching the key/length prefix for which
+// pred return true. It return the number of removed leaves.
+func (r *Tree[V])RemoveIf(key *[]byte, length int16, pred func(n *Leaf[V])(bool))(int) {
+	var i Iterator[V]
+	var count int
+
+	i.init(r, key, length)
+	for i.Next() {
+		if pred(i.Get()) {
+			i.Delete()
+			count++
+		}
+	}
+	return count
 }
@@ -0,0 +1,107 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+type aggregate_key struct {
+	key []byte
+	length int16
+}
+
+/* Push all leaves under the prefix in the worklist */
+func (r *Tree[V])aggregate_push_children(work []aggregate_key, key []byte, length int16)([]aggregate_key) {
+	var it *Iterator[V]
+	var n *Leaf[V]
+
+	it = r.NewIter(&key, length)
+	for it.Next() {
+		n = it.Get()
+		if n.node.End + 1 != length {
+			work = append(work, aggregate_key{[]byte(n.node.Bytes), n.node.End + 1})
+		}
+	}
+	return work
+}
+
+// Aggregate return a new tree containing the minimal set of prefixes
+// equivalent to the tree for the longest match lookups. Two sibling
+// prefixes are merged in their parent prefix, and a prefix covered by
+// its nearest covering prefix is removed. Prefixes are merged or
+// removed only if equal reports their data as equal. If equal is nil,
+// the data are not compared and the data of the merged prefix is the
+// data of one of them. The prefix of length 0 is never produced.
+func (r *Tree[V])Aggregate(equal func(a V, b V)(bool))(*Tree[V]) {
+	var t *Tree[V]
+	var work []aggregate_key
+	var k aggregate_key
+	var n *Leaf[V]
+	var s *Leaf[V]
+	var p *Leaf[V]
+	var path []*Leaf[V]
+	var sibling []byte
+	var parent []byte
+	var data V
+	var it *Iterator[V]
+	var i int16
+
+	if equal == nil {
+		equal = func(a V, b V)(bool) { return true }
+	}
+
+	t = NewTree[V]()
+	t.copy_from(r)
+
+	/* All the leaves are candidate */
+	it = t.NewIter(nil, 0)
+	for it.Next() {
+		n = it.Get()
+		work = append(work, aggregate_key{[]byte(n.node.Bytes), n.node.End + 1})
+	}
+
+	for len(work) > 0 {
+		k = work[len(work) - 1]
+		work = work[:len(work) - 1]
+
+		n = t.Get(&k.key, k.length)
+		if n == nil {
+			continue
+		}
+
+		/* The leaf is redundant with its nearest covering leaf. Remove
+		 * it, its children could become redundant too.
+		 */
+		path = t.LookupLonguestPath(&k.key, k.length)
+		if len(path) >= 2 && equal(path[len(path) - 2].Data, n.Data) {
+			t.Delete(n)
+			work = t.aggregate_push_children(work, k.key, k.length)
+			continue
+		}
+
+		/* Lookup the sibling, never merge in a 0 length prefix */
+		if k.length < 2 {
+			continue
+		}
+		sibling = append([]byte{}, k.key...)
+		sibling[(k.length - 1) / 8] ^= 0x80 >> ((k.length - 1) % 8)
+		s = t.Get(&sibling, k.length)
+		if s == nil || !equal(n.Data, s.Data) {
+			continue
+		}
+
+		/* Merge the two siblings in the parent. The parent is fully
+		 * covered by the siblings, so its previous data is never
+		 * used and it is replaced.
+		 */
+		data = n.Data
+		t.Delete(n)
+		t.Delete(s)
+		parent = append([]byte{}, k.key...)
+		for i = k.length - 1; i < int16(len(parent)) * 8; i++ {
+			parent[i / 8] &^= 0x80 >> (i % 8)
+		}
+		p, _ = t.Insert(&parent, k.length - 1, data)
+		p.Data = data
+		work = append(work, aggregate_key{parent, k.length - 1})
+	}
+
+	return t
+}
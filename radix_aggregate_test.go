@@ -0,0 +1,110 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "math/rand"
+import "net/netip"
+import "sort"
+import "testing"
+
+func aggregate_dump(r *Tree[string])([]string) {
+	var out []string
+	var it *Iterator[string]
+
+	it = r.NewIter(nil, 0)
+	for it.Next() {
+		out = append(out, it.Get().Prefix().String() + "=" + it.Get().Data)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestAggregate(t *testing.T) {
+	var r *Tree[string]
+	var a *Tree[string]
+	var got []string
+	var expect []string
+	var p string
+	var i int
+
+	r = NewTree[string]()
+	for _, p = range []string{
+		"10.0.0.0/25", "10.0.0.128/25", /* merged in 10.0.0.0/24 */
+		"10.0.1.0/24",                  /* merged with 10.0.0.0/24 in 10.0.0.0/23 */
+		"10.0.0.64/26",                 /* covered by 10.0.0.0/23 */
+		"10.1.0.0/16", "10.1.2.0/24",   /* covered */
+		"10.2.0.0/25", "10.2.0.128/25", /* merged, parent data replaced */
+		"1.0.0.0/1", "128.0.0.0/1",     /* never merged in 0/0 */
+	} {
+		r.PrefixInsert(netip.MustParsePrefix(p), "a")
+	}
+	r.PrefixInsert(netip.MustParsePrefix("10.2.0.0/24"), "b")
+	r.PrefixInsert(netip.MustParsePrefix("192.168.0.0/25"), "a")
+	r.PrefixInsert(netip.MustParsePrefix("192.168.0.128/25"), "b")
+	r.PrefixInsert(netip.MustParsePrefix("192.168.0.128/26"), "a")
+
+	/* Data ignored */
+	a = r.Aggregate(nil)
+	got = aggregate_dump(a)
+	if len(got) != 2 || a.Len() != 2 {
+		t.Errorf("Expect 2 prefixes, got %v", got)
+	}
+
+	/* Data compared */
+	a = r.Aggregate(func(x string, y string)(bool) { return x == y })
+	got = aggregate_dump(a)
+	expect = []string{
+		"0.0.0.0/1=a",
+		"128.0.0.0/1=a",
+		"192.168.0.128/25=b",
+		"192.168.0.128/26=a",
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("Expect %v, got %v", expect, got)
+	}
+	for i = range got {
+		if got[i] != expect[i] {
+			t.Fatalf("Expect %v, got %v", expect, got)
+		}
+	}
+
+	/* The source tree is not modified */
+	if r.Len() != 14 {
+		t.Errorf("Expect 14 prefixes in source tree, got %d", r.Len())
+	}
+}
+
+func TestAggregateRandom(t *testing.T) {
+	var r *Tree[int]
+	var a *Tree[int]
+	var n *Leaf[int]
+	var m *Leaf[int]
+	var addr netip.Addr
+	var eq func(x int, y int)(bool)
+	var i int
+
+	eq = func(x int, y int)(bool) { return x == y }
+	r = NewTree[int]()
+	for i = 0; i < 5000; i++ {
+		r.PrefixInsert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0}), 16 + rand.Intn(9)), rand.Intn(2))
+	}
+	a = r.Aggregate(eq)
+	if a.Len() > r.Len() {
+		t.Errorf("Aggregate grows the tree")
+	}
+
+	/* Lookup results must be the same */
+	for i = 0; i < 20000; i++ {
+		addr = netip.AddrFrom4([4]byte{10, byte(rand.Intn(5)), byte(rand.Intn(256)), byte(rand.Intn(256))})
+		n = r.AddrLookupLonguest(addr)
+		m = a.AddrLookupLonguest(addr)
+		if (n == nil) != (m == nil) || (n != nil && n.Data != m.Data) {
+			t.Fatalf("Lookup mismatch for %s", addr)
+		}
+	}
+
+	/* Idempotent */
+	if a.Aggregate(eq).Len() != a.Len() {
+		t.Errorf("Aggregate is not idempotent")
+	}
+}
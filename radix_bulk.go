@@ -0,0 +1,209 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "errors"
+import "fmt"
+import "iter"
+
+// ErrNotSorted is returned by BulkLoad when the input is not sorted in
+// the tree order or contains duplicates.
+var ErrNotSorted = errors.New("radix: input not sorted")
+
+// BulkEntry is a prefix and its data loaded by BulkLoad.
+type BulkEntry[V any] struct {
+	Key []byte
+	Length int16
+	Data V
+}
+
+/* Position of the next unused slot in the node and leaf pools */
+type bulk_alloc struct {
+	node_chunk int
+	node_offset int
+	leaf_chunk int
+	leaf_offset int
+}
+
+/* Return the next unused node, the slots are used in order */
+func (r *Tree[V])bulk_node(b *bulk_alloc)(*node, uint32) {
+	var ref uint32
+
+	if b.node_chunk == len(r.node.pool) {
+		r.node_growth()
+		if b.node_chunk == 0 {
+			/* skip the null node */
+			b.node_offset = 1
+		}
+	}
+	ref = uint32(b.node_chunk) << 16 | uint32(b.node_offset)
+	b.node_offset++
+	if b.node_offset == len(r.node.pool[b.node_chunk].nodes) {
+		b.node_chunk++
+		b.node_offset = 0
+	}
+	return r.r2n(ref), ref
+}
+
+/* Return the next unused leaf, the slots are used in order */
+func (r *Tree[V])bulk_leaf(b *bulk_alloc)(*Leaf[V], uint32) {
+	var ref uint32
+
+	if b.leaf_chunk == len(r.leaf.pool) {
+		r.leaf_growth()
+	}
+	ref = 0x80000000 | uint32(b.leaf_chunk) << 16 | uint32(b.leaf_offset)
+	b.leaf_offset++
+	if b.leaf_offset == len(r.leaf.pool[b.leaf_chunk].nodes) {
+		b.leaf_chunk++
+		b.leaf_offset = 0
+	}
+	return n2N[V](r.r2n(ref)), ref
+}
+
+/* Rebuild the free lists with the unused slots of the last chunks, so
+ * the next allocations continue in order.
+ */
+func (r *Tree[V])bulk_free(b *bulk_alloc) {
+	var i int
+
+	r.node.next = null
+	r.node.free = 0
+	if b.node_chunk < len(r.node.pool) {
+		for i = len(r.node.pool[b.node_chunk].nodes) - 1; i >= b.node_offset; i-- {
+			r.node.pool[b.node_chunk].nodes[i].Left = r.node.next
+			r.node.next = uint32(b.node_chunk) << 16 | uint32(i)
+			r.node.free++
+		}
+	}
+	r.leaf.next = null
+	r.leaf.free = 0
+	if b.leaf_chunk < len(r.leaf.pool) {
+		for i = len(r.leaf.pool[b.leaf_chunk].nodes) - 1; i >= b.leaf_offset; i-- {
+			r.leaf.pool[b.leaf_chunk].nodes[i].node.Left = r.leaf.next
+			r.leaf.next = 0x80000000 | uint32(b.leaf_chunk) << 16 | uint32(i)
+			r.leaf.free++
+		}
+	}
+}
+
+// BulkLoad build a tree from entries sorted in the tree order, as
+// returned by the iterators. The tree is built in one pass without
+// lookup, and the nodes are stored contiguously in the order of the
+// input. Return an error wrapping ErrNotSorted if the input is not
+// sorted or contains duplicates, or the check errors ErrZeroLength and
+// ErrKeyTooShort for invalid entries.
+func BulkLoad[V any](seq iter.Seq[BulkEntry[V]])(*Tree[V], error) {
+	var r *Tree[V]
+	var b bulk_alloc
+	var stack []uint32
+	var e BulkEntry[V]
+	var leaf *Leaf[V]
+	var ref uint32
+	var last *node
+	var n *node
+	var p *node
+	var m *node
+	var mref uint32
+	var index int
+	var l int16
+	var bit int16
+	var err error
+
+	r = NewTree[V]()
+	for e = range seq {
+		err = check_key(&e.Key, e.Length)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d", err, index)
+		}
+		if last != nil && prefix_compare(e.Key, e.Length, []byte(last.Bytes), last.End + 1) <= 0 {
+			return nil, fmt.Errorf("%w: entry %d", ErrNotSorted, index)
+		}
+		index++
+
+		leaf, ref = r.bulk_leaf(&b)
+		leaf.node.Bytes = string(e.Key)
+		leaf.node.End = e.Length - 1
+		leaf.node.Left = null
+		leaf.node.Right = null
+		leaf.Data = e.Data
+		r.length++
+
+		/* First entry is the root */
+		if last == nil {
+			leaf.node.Start = 0
+			leaf.node.Parent = null
+			r.Node = ref
+			stack = append(stack, ref)
+			last = &leaf.node
+			continue
+		}
+
+		/* The entry is after the last one, so it is its child or it
+		 * starts a right branch at the first different bit.
+		 */
+		l = e.Length
+		if last.End + 1 < l {
+			l = last.End + 1
+		}
+		bit = bitlonguestmatch(e.Key, []byte(last.Bytes), 0, l - 1)
+		if bit == -1 {
+			leaf.node.Start = last.End + 1
+			leaf.node.Parent = stack[len(stack) - 1]
+			if bitget(e.Key, leaf.node.Start) == 1 {
+				last.Right = ref
+			} else {
+				last.Left = ref
+			}
+			stack = append(stack, ref)
+			last = &leaf.node
+			continue
+		}
+
+		/* Browse the right path up to the node containing the bit */
+		for len(stack) > 1 && r.r2n(stack[len(stack) - 1]).Start > bit {
+			stack = stack[:len(stack) - 1]
+		}
+		n = r.r2n(stack[len(stack) - 1])
+		leaf.node.Start = bit
+
+		/* The bit is the first bit of the node, the parent is a
+		 * leaf without right child.
+		 */
+		if n.Start == bit && len(stack) > 1 {
+			p = r.r2n(n.Parent)
+			p.Right = ref
+			leaf.node.Parent = n.Parent
+			stack[len(stack) - 1] = ref
+			last = &leaf.node
+			continue
+		}
+
+		/* Split the node with an internal node */
+		m, mref = r.bulk_node(&b)
+		m.Bytes = n.Bytes
+		m.Start = n.Start
+		m.End = bit - 1
+		m.Parent = n.Parent
+		m.Left = stack[len(stack) - 1]
+		m.Right = ref
+		if n.Parent == null {
+			r.Node = mref
+		} else {
+			p = r.r2n(n.Parent)
+			if p.Left == m.Left {
+				p.Left = mref
+			} else {
+				p.Right = mref
+			}
+		}
+		n.Start = bit
+		n.Parent = mref
+		leaf.node.Parent = mref
+		stack[len(stack) - 1] = mref
+		stack = append(stack, ref)
+		last = &leaf.node
+	}
+	r.bulk_free(&b)
+	return r, nil
+}
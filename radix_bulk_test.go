@@ -0,0 +1,161 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "errors"
+import "iter"
+import "net"
+import "testing"
+
+func bulk_entries(r *Tree[int])(iter.Seq[BulkEntry[int]]) {
+	return func(yield func(BulkEntry[int])(bool)) {
+		var n *Leaf[int]
+
+		for n = range r.All() {
+			if !yield(BulkEntry[int]{Key: []byte(n.node.Bytes), Length: n.node.End + 1, Data: n.Data}) {
+				return
+			}
+		}
+	}
+}
+
+func bulk_slice(e []BulkEntry[int])(iter.Seq[BulkEntry[int]]) {
+	return func(yield func(BulkEntry[int])(bool)) {
+		var i int
+
+		for i = range e {
+			if !yield(e[i]) {
+				return
+			}
+		}
+	}
+}
+
+/* Compare the shape of two trees */
+func bulk_same(t *testing.T, a *Tree[int], aref uint32, b *Tree[int], bref uint32) {
+	var an *node
+	var bn *node
+
+	if (aref == null) != (bref == null) {
+		t.Fatalf("Child mismatch %08x %08x", aref, bref)
+	}
+	if aref == null {
+		return
+	}
+	if is_leaf(aref) != is_leaf(bref) {
+		t.Fatalf("Kind mismatch %08x %08x", aref, bref)
+	}
+	an = a.r2n(aref)
+	bn = b.r2n(bref)
+	if an.Start != bn.Start || an.End != bn.End {
+		t.Fatalf("Node mismatch %d/%d and %d/%d", an.Start, an.End, bn.Start, bn.End)
+	}
+	bulk_same(t, a, an.Left, b, bn.Left)
+	bulk_same(t, a, an.Right, b, bn.Right)
+}
+
+func TestBulkLoad(t *testing.T) {
+	var r *Tree[int]
+	var l *Tree[int]
+	var n *Leaf[int]
+	var prev uint32
+	var ref uint32
+	var count int
+	var nw *net.IPNet
+	var err error
+
+	/* Empty input */
+	l, err = BulkLoad(bulk_slice(nil))
+	if err != nil || l.Len() != 0 || l.First() != nil {
+		t.Fatalf("Empty load failed: %v", err)
+	}
+
+	/* Load a random tree */
+	r = verify_random_tree()
+	l, err = BulkLoad(bulk_entries(r))
+	if err != nil {
+		t.Fatalf("BulkLoad: %v", err)
+	}
+	err = l.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if l.Len() != r.Len() {
+		t.Fatalf("Expect %d leaves, got %d", r.Len(), l.Len())
+	}
+	bulk_same(t, r, r.Node, l, l.Node)
+	Diff(r, l, func(a int, b int)(bool) { return a == b }, func(kind DiffKind, o *Leaf[int], n *Leaf[int])(bool) {
+		t.Errorf("Unexpected difference %d", kind)
+		return false
+	})
+
+	/* Leaves are stored in the input order */
+	for n = range l.All() {
+		ref = l.n2r(&n.node)
+		if count > 0 && ref != prev + 1 && ref & 0xffff != 0 {
+			t.Fatalf("Leaf %08x does not follow %08x", ref, prev)
+		}
+		prev = ref
+		count++
+	}
+
+	/* The tree can be modified after the load */
+	nw = &net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(30, 32)}
+	l.IPv4Insert(nw, -1)
+	l.Delete(l.First())
+	err = l.Verify()
+	if err != nil {
+		t.Fatalf("Verify after update: %v", err)
+	}
+}
+
+func TestBulkLoadError(t *testing.T) {
+	var e []BulkEntry[int]
+	var err error
+
+	/* Children are after their parent */
+	e = []BulkEntry[int]{
+		{Key: []byte{0x0a, 0x01}, Length: 16},
+		{Key: []byte{0x0a, 0x00}, Length: 8},
+	}
+	_, err = BulkLoad(bulk_slice(e))
+	if !errors.Is(err, ErrNotSorted) {
+		t.Errorf("Expect ErrNotSorted, got %v", err)
+	}
+
+	/* Left before right */
+	e = []BulkEntry[int]{
+		{Key: []byte{0x80}, Length: 1},
+		{Key: []byte{0x00}, Length: 1},
+	}
+	_, err = BulkLoad(bulk_slice(e))
+	if !errors.Is(err, ErrNotSorted) {
+		t.Errorf("Expect ErrNotSorted, got %v", err)
+	}
+
+	/* Duplicates */
+	e = []BulkEntry[int]{
+		{Key: []byte{0x0a}, Length: 8},
+		{Key: []byte{0x0a}, Length: 8},
+	}
+	_, err = BulkLoad(bulk_slice(e))
+	if !errors.Is(err, ErrNotSorted) {
+		t.Errorf("Expect ErrNotSorted, got %v", err)
+	}
+
+	/* Invalid entries */
+	e = []BulkEntry[int]{
+		{Key: []byte{0x0a}, Length: 0},
+	}
+	_, err = BulkLoad(bulk_slice(e))
+	if !errors.Is(err, ErrZeroLength) {
+		t.Errorf("Expect ErrZeroLength, got %v", err)
+	}
+	e = []BulkEntry[int]{
+		{Key: []byte{0x0a}, Length: 16},
+	}
+	_, err = BulkLoad(bulk_slice(e))
+	if !errors.Is(err, ErrKeyTooShort) {
+		t.Errorf("Expect ErrKeyTooShort, got %v", err)
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "errors"
+import "net"
+import "net/netip"
+import "time"
+import "unsafe"
+
+// ErrZeroLength is returned when the prefix length is 0 or negative.
+var ErrZeroLength = errors.New("radix: zero length prefix")
+
+// ErrKeyTooLong is returned when the key exceed the 32767 bits allowed.
+var ErrKeyTooLong = errors.New("radix: key too long")
+
+// ErrKeyTooShort is returned when the key has less bits than the length.
+var ErrKeyTooShort = errors.New("radix: key shorter than length")
+
+// ErrWrongFamily is returned when a network is not of the expected family.
+var ErrWrongFamily = errors.New("radix: wrong address family")
+
+// ErrCapacity is returned when the tree reach the maximum number of node.
+var ErrCapacity = errors.New("radix: maximum capacity reached")
+
+// ErrUnknownNode is returned when the leaf is not a leaf of the tree.
+var ErrUnknownNode = errors.New("radix: unknown node")
+
+// Checked is a view on a tree where the functions return errors in
+// place of panics or nil results on invalid input. It is obtained with
+// Tree.Checked and it use the same tree.
+type Checked[V any] struct {
+	r *Tree[V]
+}
+
+// Checked return the error returning view of the tree.
+func (r *Tree[V])Checked()(Checked[V]) {
+	return Checked[V]{r: r}
+}
+
+func check_key(key *[]byte, length int16)(error) {
+	if length <= 0 {
+		return ErrZeroLength
+	}
+	if key == nil || int(length) > len(*key) * 8 {
+		return ErrKeyTooShort
+	}
+	return nil
+}
+
+func check_string(str string)(error) {
+	if len(str) == 0 {
+		return ErrZeroLength
+	}
+	if len(str) * 8 > 32767 {
+		return ErrKeyTooLong
+	}
+	return nil
+}
+
+func check_ipv4(network *net.IPNet)([]byte, int16, error) {
+	var key []byte
+	var length int16
+
+	if network == nil {
+		return nil, 0, ErrWrongFamily
+	}
+	key, length = network_to_key(network)
+	if key == nil {
+		return nil, 0, ErrWrongFamily
+	}
+	if length == 0 {
+		return nil, 0, ErrZeroLength
+	}
+	return key, length, nil
+}
+
+func check_ipv6(network *net.IPNet)([]byte, int16, error) {
+	var key []byte
+	var length int16
+
+	if network == nil {
+		return nil, 0, ErrWrongFamily
+	}
+	key, length = network6_to_key(network)
+	if key == nil {
+		return nil, 0, ErrWrongFamily
+	}
+	if length == 0 {
+		return nil, 0, ErrZeroLength
+	}
+	return key, length, nil
+}
+
+/* An insert allocates at most one leaf and one node */
+func (c Checked[V])check_capacity()(error) {
+	if c.r.leaf.free == 0 && len(c.r.leaf.pool) >= 32768 {
+		return ErrCapacity
+	}
+	if c.r.node.free == 0 && len(c.r.node.pool) >= 32768 {
+		return ErrCapacity
+	}
+	return nil
+}
+
+/* Return true if the leaf is stored in the pools of the tree */
+func (c Checked[V])owns(n *Leaf[V])(bool) {
+	var p uintptr
+	var pr ptr_range
+
+	if n == nil {
+		return false
+	}
+	p = uintptr(unsafe.Pointer(n))
+	for _, pr = range c.r.ptr_range {
+		if pr.kind == kind_leaf && p >= pr.start && p <= pr.end {
+			return (p - pr.start) % uintptr(leaf_size[V]()) == 0
+		}
+	}
+	return false
+}
+
+// Insert key/length prefix in the tree. If the prefix already exists in
+// the tree, return existing leaf and false.
+func (c Checked[V])Insert(key *[]byte, length int16, data V)(*Leaf[V], bool, error) {
+	var n *Leaf[V]
+	var ok bool
+	var err error
+
+	err = check_key(key, length)
+	if err != nil {
+		return nil, false, err
+	}
+	err = c.check_capacity()
+	if err != nil {
+		return nil, false, err
+	}
+	n, ok = c.r.Insert(key, length, data)
+	return n, ok, nil
+}
+
+// Get return exact match of the key/length prefix. Return nil without
+// error if the prefix does not exists.
+func (c Checked[V])Get(key *[]byte, length int16)(*Leaf[V], error) {
+	var err error
+
+	err = check_key(key, length)
+	if err != nil {
+		return nil, err
+	}
+	return c.r.Get(key, length), nil
+}
+
+// LookupLonguest return the leaf which match the longest part of the
+// key/length prefix. Return nil without error if none match.
+func (c Checked[V])LookupLonguest(key *[]byte, length int16)(*Leaf[V], error) {
+	var err error
+
+	err = check_key(key, length)
+	if err != nil {
+		return nil, err
+	}
+	return c.r.LookupLonguest(key, length), nil
+}
+
+// Delete remove the key/length prefix. Return true if the prefix was
+// removed.
+func (c Checked[V])Delete(key *[]byte, length int16)(bool, error) {
+	var n *Leaf[V]
+	var err error
+
+	n, err = c.Get(key, length)
+	if err != nil || n == nil {
+		return false, err
+	}
+	c.r.Delete(n)
+	return true, nil
+}
+
+// DeleteLeaf remove the leaf from the tree. Return ErrUnknownNode if the
+// leaf is not stored in the tree.
+func (c Checked[V])DeleteLeaf(n *Leaf[V])(error) {
+	if !c.owns(n) || (c.r.Node != c.r.n2r(&n.node) && n.node.Parent == null) {
+		return ErrUnknownNode
+	}
+	c.r.Delete(n)
+	return nil
+}
+
+// IPv4Insert insert ipv4 network in the tree.
+func (c Checked[V])IPv4Insert(network *net.IPNet, data V)(*Leaf[V], bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv4(network)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.Insert(&key, length, data)
+}
+
+// IPv4Get return exact match of the ipv4 network.
+func (c Checked[V])IPv4Get(network *net.IPNet)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv4(network)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(&key, length)
+}
+
+// IPv4LookupLonguest return the leaf which match the longest part of the
+// ipv4 network.
+func (c Checked[V])IPv4LookupLonguest(network *net.IPNet)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv4(network)
+	if err != nil {
+		return nil, err
+	}
+	return c.LookupLonguest(&key, length)
+}
+
+// IPv4Delete remove the ipv4 network. Return true if the network was
+// removed.
+func (c Checked[V])IPv4Delete(network *net.IPNet)(bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv4(network)
+	if err != nil {
+		return false, err
+	}
+	return c.Delete(&key, length)
+}
+
+// IPv6Insert insert ipv6 network in the tree.
+func (c Checked[V])IPv6Insert(network *net.IPNet, data V)(*Leaf[V], bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv6(network)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.Insert(&key, length, data)
+}
+
+// IPv6Get return exact match of the ipv6 network.
+func (c Checked[V])IPv6Get(network *net.IPNet)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv6(network)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(&key, length)
+}
+
+// IPv6LookupLonguest return the leaf which match the longest part of the
+// ipv6 network.
+func (c Checked[V])IPv6LookupLonguest(network *net.IPNet)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv6(network)
+	if err != nil {
+		return nil, err
+	}
+	return c.LookupLonguest(&key, length)
+}
+
+// IPv6Delete remove the ipv6 network. Return true if the network was
+// removed.
+func (c Checked[V])IPv6Delete(network *net.IPNet)(bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	key, length, err = check_ipv6(network)
+	if err != nil {
+		return false, err
+	}
+	return c.Delete(&key, length)
+}
+
+// PrefixInsert insert IPv4 or IPv6 prefix in the tree. A tree indexes
+// only one family, ErrWrongFamily is returned if the prefix is not of the
+// family of the tree. This is true for all the Prefix and Addr functions.
+func (c Checked[V])PrefixInsert(prefix netip.Prefix, data V)(*Leaf[V], bool, error) {
+	var buf [16]byte
+	var key []byte
+	var length int16
+
+	if !prefix.IsValid() {
+		return nil, false, ErrWrongFamily
+	}
+	key, length = prefix_to_key(prefix.Masked(), &buf)
+	if !c.r.same_family(key) {
+		return nil, false, ErrWrongFamily
+	}
+	return c.Insert(&key, length, data)
+}
+
+// PrefixGet return exact match of the IPv4 or IPv6 prefix.
+func (c Checked[V])PrefixGet(prefix netip.Prefix)(*Leaf[V], error) {
+	var buf [16]byte
+	var key []byte
+	var length int16
+
+	if !prefix.IsValid() {
+		return nil, ErrWrongFamily
+	}
+	key, length = prefix_to_key(prefix, &buf)
+	if !c.r.same_family(key) {
+		return nil, ErrWrongFamily
+	}
+	return c.Get(&key, length)
+}
+
+// AddrLookupLonguest return the leaf which match the longest part of the
+// IPv4 or IPv6 address.
+func (c Checked[V])AddrLookupLonguest(addr netip.Addr)(*Leaf[V], error) {
+	var buf [16]byte
+	var key []byte
+
+	if !addr.IsValid() {
+		return nil, ErrWrongFamily
+	}
+	key = addr_to_key(addr, &buf)
+	if !c.r.same_family(key) {
+		return nil, ErrWrongFamily
+	}
+	return c.LookupLonguest(&key, int16(addr.BitLen()))
+}
+
+// PrefixDelete remove the IPv4 or IPv6 prefix. Return true if the prefix
+// was removed.
+func (c Checked[V])PrefixDelete(prefix netip.Prefix)(bool, error) {
+	var buf [16]byte
+	var key []byte
+	var length int16
+
+	if !prefix.IsValid() {
+		return false, ErrWrongFamily
+	}
+	key, length = prefix_to_key(prefix, &buf)
+	if !c.r.same_family(key) {
+		return false, ErrWrongFamily
+	}
+	return c.Delete(&key, length)
+}
+
+// StringInsert insert string in the tree.
+func (c Checked[V])StringInsert(str string, data V)(*Leaf[V], bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	err = check_string(str)
+	if err != nil {
+		return nil, false, err
+	}
+	key, length = string_to_key(str)
+	return c.Insert(&key, length, data)
+}
+
+// StringGet return exact match of the string.
+func (c Checked[V])StringGet(str string)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	err = check_string(str)
+	if err != nil {
+		return nil, err
+	}
+	key, length = string_to_key(str)
+	return c.Get(&key, length)
+}
+
+// StringLookupLonguest return the leaf which match the longest part of
+// the string.
+func (c Checked[V])StringLookupLonguest(str string)(*Leaf[V], error) {
+	var key []byte
+	var length int16
+	var err error
+
+	err = check_string(str)
+	if err != nil {
+		return nil, err
+	}
+	key, length = string_to_key(str)
+	return c.LookupLonguest(&key, length)
+}
+
+// StringDelete remove the string. Return true if the string was removed.
+func (c Checked[V])StringDelete(str string)(bool, error) {
+	var key []byte
+	var length int16
+	var err error
+
+	err = check_string(str)
+	if err != nil {
+		return false, err
+	}
+	key, length = string_to_key(str)
+	return c.Delete(&key, length)
+}
+
+// UInt64Insert insert uint64 value in the tree.
+func (c Checked[V])UInt64Insert(value uint64, data V)(*Leaf[V], bool, error) {
+	var key []byte
+
+	key = uint64_to_key(value)
+	return c.Insert(&key, length, data)
+}
+
+// UInt64Get return exact match of the uint64 value.
+func (c Checked[V])UInt64Get(value uint64)(*Leaf[V], error) {
+	var key []byte
+
+	key = uint64_to_key(value)
+	return c.Get(&key, length)
+}
+
+// UInt64Delete remove the uint64 value. Return true if the value was
+// removed.
+func (c Checked[V])UInt64Delete(value uint64)(bool, error) {
+	var key []byte
+
+	key = uint64_to_key(value)
+	return c.Delete(&key, length)
+}
+
+// TimeInsert insert date in the tree.
+func (c Checked[V])TimeInsert(value time.Time, data V)(*Leaf[V], bool, error) {
+	var key []byte
+
+	key = time_to_key(value)
+	return c.Insert(&key, time_length, data)
+}
+
+// TimeGet return exact match of the date.
+func (c Checked[V])TimeGet(value time.Time)(*Leaf[V], error) {
+	var key []byte
+
+	key = time_to_key(value)
+	return c.Get(&key, time_length)
+}
+
+// TimeDelete remove the date. Return true if the date was removed.
+func (c Checked[V])TimeDelete(value time.Time)(bool, error) {
+	var key []byte
+
+	key = time_to_key(value)
+	return c.Delete(&key, time_length)
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "errors"
+import "net"
+import "net/netip"
+import "strings"
+import "testing"
+
+func TestChecked(t *testing.T) {
+	var r *Tree[int]
+	var o *Tree[int]
+	var c Checked[int]
+	var n *Leaf[int]
+	var nw4 *net.IPNet
+	var nw6 *net.IPNet
+	var key []byte
+	var ok bool
+	var err error
+
+	r = NewTree[int]()
+	c = r.Checked()
+	_, nw4, _ = net.ParseCIDR("10.0.0.0/8")
+	_, nw6, _ = net.ParseCIDR("2001:db8::/32")
+
+	/* Invalid input */
+	key = []byte{1, 2}
+	_, _, err = c.Insert(&key, 0, 1)
+	if !errors.Is(err, ErrZeroLength) {
+		t.Errorf("Expect ErrZeroLength, got %v", err)
+	}
+	_, _, err = c.Insert(&key, 17, 1)
+	if !errors.Is(err, ErrKeyTooShort) {
+		t.Errorf("Expect ErrKeyTooShort, got %v", err)
+	}
+	_, err = c.Get(nil, 8)
+	if !errors.Is(err, ErrKeyTooShort) {
+		t.Errorf("Expect ErrKeyTooShort, got %v", err)
+	}
+	_, _, err = c.IPv4Insert(nw6, 1)
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, err = c.IPv6Get(nw4)
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, err = c.IPv4LookupLonguest(nil)
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, _, err = c.IPv4Insert(&net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}, 1)
+	if !errors.Is(err, ErrZeroLength) {
+		t.Errorf("Expect ErrZeroLength, got %v", err)
+	}
+	_, _, err = c.StringInsert(strings.Repeat("a", 4096), 1)
+	if !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("Expect ErrKeyTooLong, got %v", err)
+	}
+	_, err = c.StringGet("")
+	if !errors.Is(err, ErrZeroLength) {
+		t.Errorf("Expect ErrZeroLength, got %v", err)
+	}
+	_, err = c.PrefixGet(netip.Prefix{})
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("Expect empty tree")
+	}
+
+	/* Valid input */
+	_, ok, err = c.IPv4Insert(nw4, 4)
+	if err != nil || !ok {
+		t.Fatalf("Unexpected insert result %v %v", ok, err)
+	}
+	_, ok, err = c.IPv6Insert(nw6, 6)
+	if err != nil || !ok {
+		t.Fatalf("Unexpected insert result %v %v", ok, err)
+	}
+	_, ok, err = c.StringInsert(strings.Repeat("a", 4095), 1)
+	if err != nil || !ok {
+		t.Fatalf("Unexpected insert result %v %v", ok, err)
+	}
+	n, err = c.IPv4LookupLonguest(&net.IPNet{IP: net.IPv4(10, 1, 2, 3).To4(), Mask: net.CIDRMask(32, 32)})
+	if err != nil || n == nil || n.Data != 4 {
+		t.Errorf("Unexpected lookup result %v", err)
+	}
+	n, err = c.IPv6Get(nw6)
+	if err != nil || n == nil || n.Data != 6 {
+		t.Errorf("Unexpected get result %v", err)
+	}
+	ok, err = c.IPv4Delete(nw4)
+	if err != nil || !ok {
+		t.Errorf("Unexpected delete result %v %v", ok, err)
+	}
+	ok, err = c.IPv4Delete(nw4)
+	if err != nil || ok {
+		t.Errorf("Unexpected delete result %v %v", ok, err)
+	}
+
+	/* Leaf of another tree or already deleted */
+	o = NewTree[int]()
+	n, _ = o.IPv4Insert(nw4, 1)
+	err = c.DeleteLeaf(n)
+	if !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expect ErrUnknownNode, got %v", err)
+	}
+	n = r.IPv6Get(nw6)
+	err = c.DeleteLeaf(n)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	err = c.DeleteLeaf(n)
+	if !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expect ErrUnknownNode, got %v", err)
+	}
+	if r.Len() != 1 {
+		t.Errorf("Expect 1 leaf, got %d", r.Len())
+	}
+}
+
+func TestCheckedFamily(t *testing.T) {
+	var r *Tree[int]
+	var c Checked[int]
+	var n *Leaf[int]
+	var ok bool
+	var err error
+
+	r = NewTree[int]()
+	c = r.Checked()
+
+	/* The first prefix set the family of the tree */
+	_, ok, err = c.PrefixInsert(netip.MustParsePrefix("10.0.0.0/8"), 4)
+	if err != nil || !ok {
+		t.Fatalf("Unexpected insert result %v %v", ok, err)
+	}
+
+	/* 10.0.0.0/8 and a00::/8 have the same key */
+	_, _, err = c.PrefixInsert(netip.MustParsePrefix("a00::/8"), 6)
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, err = c.PrefixGet(netip.MustParsePrefix("a00::/8"))
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, err = c.AddrLookupLonguest(netip.MustParseAddr("a01::1"))
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	_, err = c.PrefixDelete(netip.MustParsePrefix("a00::/8"))
+	if !errors.Is(err, ErrWrongFamily) {
+		t.Errorf("Expect ErrWrongFamily, got %v", err)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("Expect 1 leaf, got %d", r.Len())
+	}
+
+	/* The family of the tree is accepted */
+	n, err = c.AddrLookupLonguest(netip.MustParseAddr("10.1.2.3"))
+	if err != nil || n == nil || n.Data != 4 {
+		t.Errorf("Unexpected lookup result %v", err)
+	}
+	n, err = c.PrefixGet(netip.MustParsePrefix("10.0.0.0/8"))
+	if err != nil || n == nil || n.Data != 4 {
+		t.Errorf("Unexpected get result %v", err)
+	}
+	ok, err = c.PrefixDelete(netip.MustParsePrefix("10.0.0.0/8"))
+	if err != nil || !ok {
+		t.Errorf("Unexpected delete result %v %v", ok, err)
+	}
+
+	/* An empty tree accepts both families */
+	_, ok, err = c.PrefixInsert(netip.MustParsePrefix("a00::/8"), 6)
+	if err != nil || !ok {
+		t.Errorf("Unexpected insert result %v %v", ok, err)
+	}
+}
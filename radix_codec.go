@@ -0,0 +1,413 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bufio"
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "hash"
+import "hash/crc32"
+import "io"
+
+/* Binary format, all integer are big endian:
+ *
+ *   header:
+ *      magic    [4]byte "GRDX"
+ *      version  uint16
+ *      flags    uint16  bit 0: data are encoded
+ *      length   uint64  number of leaf
+ *      nodes    uint64  number of node and leaf
+ *
+ *   nodes, in tree order, each node is followed by its left
+ *   and right children:
+ *      kind     byte    bit 0: leaf, bit 1: left child, bit 2: right child
+ *      start    int16
+ *      end      int16
+ *      key len  uint16
+ *      key      [key len]byte
+ *      data len uint32 (only for leaf if data are encoded)
+ *      data     [data len]byte
+ *
+ *   trailer:
+ *      crc32    uint32  IEEE checksum of all previous bytes
+ */
+
+const codec_magic = "GRDX"
+const codec_version = 1
+
+const codec_flag_data = 0x0001
+
+const codec_kind_leaf = 0x01
+const codec_kind_left = 0x02
+const codec_kind_right = 0x04
+
+// ErrFormat is returned by ReadFrom when the input is not a valid tree.
+var ErrFormat = errors.New("radix: invalid binary format")
+
+// Codec convert leaf data from and to bytes for the binary
+// serialization of the tree.
+type Codec[V any] interface {
+	Marshal(v V)([]byte, error)
+	Unmarshal(b []byte)(V, error)
+}
+
+// SetCodec define the codec used by WriteTo and ReadFrom for the leaf
+// data. Without codec, the data are not serialized and the loaded leaves
+// contains the zero value.
+func (r *Tree[V])SetCodec(codec Codec[V]) {
+	r.codec = codec
+}
+
+type codec_writer struct {
+	w *bufio.Writer
+	crc hash.Hash32
+	n int64
+	err error
+	buf [8]byte
+}
+
+func (c *codec_writer)write(b []byte) {
+	if c.err != nil {
+		return
+	}
+	_, c.err = c.w.Write(b)
+	c.crc.Write(b)
+	c.n += int64(len(b))
+}
+
+func (c *codec_writer)u8(v uint8) {
+	c.buf[0] = v
+	c.write(c.buf[:1])
+}
+
+func (c *codec_writer)u16(v uint16) {
+	binary.BigEndian.PutUint16(c.buf[:2], v)
+	c.write(c.buf[:2])
+}
+
+func (c *codec_writer)u32(v uint32) {
+	binary.BigEndian.PutUint32(c.buf[:4], v)
+	c.write(c.buf[:4])
+}
+
+func (c *codec_writer)u64(v uint64) {
+	binary.BigEndian.PutUint64(c.buf[:8], v)
+	c.write(c.buf[:8])
+}
+
+type codec_reader struct {
+	r *bufio.Reader
+	crc hash.Hash32
+	n int64
+	err error
+	buf [8]byte
+}
+
+/* A truncated input is a format error */
+func codec_truncated(err error)(error) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated input", ErrFormat)
+	}
+	return err
+}
+
+func (c *codec_reader)read(b []byte) {
+	if c.err != nil {
+		return
+	}
+	_, c.err = io.ReadFull(c.r, b)
+	c.err = codec_truncated(c.err)
+	c.crc.Write(b)
+	c.n += int64(len(b))
+}
+
+/* Read a block of n bytes. The buffer grows while data is read,
+ * so a corrupted length does not allocate a huge buffer.
+ */
+func (c *codec_reader)block(n int64)([]byte) {
+	var b bytes.Buffer
+
+	if c.err != nil {
+		return nil
+	}
+	_, c.err = io.CopyN(&b, c.r, n)
+	c.err = codec_truncated(c.err)
+	c.crc.Write(b.Bytes())
+	c.n += int64(b.Len())
+	return b.Bytes()
+}
+
+func (c *codec_reader)u8()(uint8) {
+	c.read(c.buf[:1])
+	return c.buf[0]
+}
+
+func (c *codec_reader)u16()(uint16) {
+	c.read(c.buf[:2])
+	return binary.BigEndian.Uint16(c.buf[:2])
+}
+
+func (c *codec_reader)u32()(uint32) {
+	c.read(c.buf[:4])
+	return binary.BigEndian.Uint32(c.buf[:4])
+}
+
+func (c *codec_reader)u64()(uint64) {
+	c.read(c.buf[:8])
+	return binary.BigEndian.Uint64(c.buf[:8])
+}
+
+/* count nodes and leaves */
+func (r *Tree[V])count_nodes(ref uint32)(uint64) {
+	var n *node
+
+	if ref == null {
+		return 0
+	}
+	n = r.r2n(ref)
+	return 1 + r.count_nodes(n.Left) + r.count_nodes(n.Right)
+}
+
+func (r *Tree[V])write_node(c *codec_writer, ref uint32) {
+	var n *node
+	var kind uint8
+	var data []byte
+
+	n = r.r2n(ref)
+	if is_leaf(ref) {
+		kind |= codec_kind_leaf
+	}
+	if n.Left != null {
+		kind |= codec_kind_left
+	}
+	if n.Right != null {
+		kind |= codec_kind_right
+	}
+	c.u8(kind)
+	c.u16(uint16(n.Start))
+	c.u16(uint16(n.End))
+	c.u16(uint16(len(n.Bytes)))
+	c.write([]byte(n.Bytes))
+	if is_leaf(ref) && r.codec != nil && c.err == nil {
+		data, c.err = r.codec.Marshal(n2N[V](n).Data)
+		c.u32(uint32(len(data)))
+		c.write(data)
+	}
+	if c.err != nil {
+		return
+	}
+	if n.Left != null {
+		r.write_node(c, n.Left)
+	}
+	if n.Right != null {
+		r.write_node(c, n.Right)
+	}
+}
+
+// WriteTo write the tree in w using a versioned and checksummed binary
+// format. The format contains keys, prefix length and the tree shape, so
+// the tree is loaded without any insert. The leaf data are encoded with
+// the codec defined by SetCodec. It return the number of bytes written.
+func (r *Tree[V])WriteTo(w io.Writer)(int64, error) {
+	var c *codec_writer
+	var flags uint16
+
+	c = &codec_writer{
+		w: bufio.NewWriter(w),
+		crc: crc32.NewIEEE(),
+	}
+
+	if r.codec != nil {
+		flags |= codec_flag_data
+	}
+
+	/* header */
+	c.write([]byte(codec_magic))
+	c.u16(codec_version)
+	c.u16(flags)
+	c.u64(uint64(r.length))
+	c.u64(r.count_nodes(r.Node))
+
+	/* nodes */
+	if r.Node != null {
+		r.write_node(c, r.Node)
+	}
+
+	/* trailer, the checksum is not included in itself */
+	binary.BigEndian.PutUint32(c.buf[:4], c.crc.Sum32())
+	c.write(c.buf[:4])
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+	return c.n, c.err
+}
+
+/* Read node and its children. return the node reference. */
+func (r *Tree[V])read_node(c *codec_reader, parent uint32, start int16, flags uint16, remain *uint64, leaves *int)(uint32) {
+	var n *node
+	var leaf *Leaf[V]
+	var kind uint8
+	var key []byte
+	var data []byte
+	var ref uint32
+	var end int16
+	var size uint16
+
+	if *remain == 0 {
+		c.err = fmt.Errorf("%w: too many nodes", ErrFormat)
+		return null
+	}
+	(*remain)--
+
+	kind = c.u8()
+	if int16(c.u16()) != start {
+		c.err = fmt.Errorf("%w: start bit does not follow parent", ErrFormat)
+	}
+	end = int16(c.u16())
+	size = c.u16()
+	if c.err == nil && int(size) * 8 > 32768 {
+		c.err = fmt.Errorf("%w: key too long", ErrFormat)
+	}
+	key = c.block(int64(size))
+	if c.err != nil {
+		return null
+	}
+	if end < start - 1 || int(end) >= len(key) * 8 {
+		c.err = fmt.Errorf("%w: inconsistent key length", ErrFormat)
+		return null
+	}
+
+	/* Only the internal root could have no bit, otherwise the start bit
+	 * strictly increase with the depth and the recursion is bounded by
+	 * the key length.
+	 */
+	if end < start && (kind & codec_kind_leaf != 0 || parent != null) {
+		c.err = fmt.Errorf("%w: node without bit", ErrFormat)
+		return null
+	}
+	if end == 32767 && kind & (codec_kind_left | codec_kind_right) != 0 {
+		c.err = fmt.Errorf("%w: children after the last bit", ErrFormat)
+		return null
+	}
+
+	/* Allocate node */
+	if kind & codec_kind_leaf != 0 {
+		leaf = r.leaf_alloc()
+		n = &leaf.node
+		*leaves++
+		if flags & codec_flag_data != 0 {
+			data = c.block(int64(c.u32()))
+			if c.err == nil && r.codec != nil {
+				leaf.Data, c.err = r.codec.Unmarshal(data)
+				if c.err != nil {
+					c.err = fmt.Errorf("%w: %w", ErrFormat, c.err)
+				}
+			}
+		}
+	} else {
+		if kind & (codec_kind_left | codec_kind_right) != codec_kind_left | codec_kind_right {
+			c.err = fmt.Errorf("%w: internal node without two children", ErrFormat)
+			return null
+		}
+		n = r.node_alloc()
+	}
+	ref = r.n2r(n)
+	n.Bytes = string(key)
+	n.Start = start
+	n.End = end
+	n.Parent = parent
+	n.Left = null
+	n.Right = null
+
+	/* Read children */
+	if c.err == nil && kind & codec_kind_left != 0 {
+		n.Left = r.read_node(c, ref, end + 1, flags, remain, leaves)
+	}
+	if c.err == nil && kind & codec_kind_right != 0 {
+		n.Right = r.read_node(c, ref, end + 1, flags, remain, leaves)
+	}
+	return ref
+}
+
+// ReadFrom replace the content of the tree by the tree read from rd. rd
+// must contain data produced by WriteTo. The node and leaf pools are
+// rebuilt directly from the tree shape. The leaf data are decoded with
+// the codec defined by SetCodec. It return the number of bytes read. A
+// corrupted or truncated input returns an error wrapping ErrFormat. On
+// error, the tree is empty.
+func (r *Tree[V])ReadFrom(rd io.Reader)(int64, error) {
+	var c *codec_reader
+	var magic [4]byte
+	var version uint16
+	var flags uint16
+	var length uint64
+	var remain uint64
+	var leaves int
+	var sum uint32
+	var codec Codec[V]
+	var node_first int
+	var leaf_first int
+	var counted bool
+
+	c = &codec_reader{
+		r: bufio.NewReader(rd),
+		crc: crc32.NewIEEE(),
+	}
+
+	/* Reset the tree */
+	codec = r.codec
+	node_first = r.node.first
+	leaf_first = r.leaf.first
+	counted = r.counted
+	*r = Tree[V]{}
+	r.codec = codec
+	r.node.first = node_first
+	r.leaf.first = leaf_first
+	r.counted = counted
+
+	/* header */
+	c.read(magic[:])
+	version = c.u16()
+	flags = c.u16()
+	length = c.u64()
+	remain = c.u64()
+	if c.err != nil {
+		return c.n, c.err
+	}
+	if string(magic[:]) != codec_magic {
+		return c.n, fmt.Errorf("%w: bad magic", ErrFormat)
+	}
+	if version != codec_version {
+		return c.n, fmt.Errorf("%w: unsupported version %d", ErrFormat, version)
+	}
+
+	/* nodes */
+	if remain != 0 {
+		r.Node = r.read_node(c, null, 0, flags, &remain, &leaves)
+	}
+	if c.err == nil && (remain != 0 || uint64(leaves) != length) {
+		c.err = fmt.Errorf("%w: node count mismatch", ErrFormat)
+	}
+
+	/* trailer */
+	sum = c.crc.Sum32()
+	if c.u32() != sum && c.err == nil {
+		c.err = fmt.Errorf("%w: bad checksum", ErrFormat)
+	}
+	if c.err != nil {
+		*r = Tree[V]{}
+		r.codec = codec
+		r.node.first = node_first
+		r.leaf.first = leaf_first
+		r.counted = counted
+		return c.n, c.err
+	}
+
+	r.length = leaves
+	if r.counted {
+		r.count_build(r.Node)
+	}
+	return c.n, nil
+}
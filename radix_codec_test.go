@@ -0,0 +1,164 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "hash/crc32"
+import "math/rand"
+import "net"
+import "testing"
+
+type string_codec struct {}
+
+func (string_codec)Marshal(v string)([]byte, error) {
+	return []byte(v), nil
+}
+
+func (string_codec)Unmarshal(b []byte)(string, error) {
+	return string(b), nil
+}
+
+func TestCodec(t *testing.T) {
+	var r *Tree[string]
+	var l *Tree[string]
+	var buf bytes.Buffer
+	var n *Leaf[string]
+	var m *Leaf[string]
+	var nw *net.IPNet
+	var b []byte
+	var wn int64
+	var rn int64
+	var err error
+	var i int
+
+	r = NewTree[string]()
+	r.SetCodec(string_codec{})
+	for i = 0; i < 10000; i++ {
+		nw = &net.IPNet{}
+		nw.IP = net.IPv4(byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), 0)
+		nw.Mask = net.CIDRMask(8 + rand.Intn(17), 32)
+		r.IPv4Insert(nw, nw.String())
+	}
+
+	wn, err = r.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if wn != int64(buf.Len()) {
+		t.Errorf("Expect %d bytes written, got %d", buf.Len(), wn)
+	}
+	b = append([]byte{}, buf.Bytes()...)
+
+	/* Load the tree and compare */
+	l = NewTree[string]()
+	l.SetCodec(string_codec{})
+	rn, err = l.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if rn != wn {
+		t.Errorf("Expect %d bytes read, got %d", wn, rn)
+	}
+	if l.Len() != r.Len() {
+		t.Errorf("Expect %d entries, got %d", r.Len(), l.Len())
+	}
+	m = l.First()
+	for n = r.First(); n != nil; n = r.Next(n) {
+		if m == nil || !Equal(n, m) || n.Data != m.Data {
+			t.Fatalf("Loaded tree differs from original")
+		}
+		m = l.Next(m)
+	}
+	if m != nil {
+		t.Errorf("Loaded tree has more entries")
+	}
+	_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+	l.IPv4Insert(nw, "10.0.0.0/8")
+	if l.IPv4Get(nw) == nil {
+		t.Errorf("Loaded tree should accept new entries")
+	}
+
+	/* Without codec data are not loaded */
+	l = NewTree[string]()
+	_, err = l.ReadFrom(bytes.NewReader(b))
+	if err != nil || l.Len() != r.Len() || l.First().Data != "" {
+		t.Errorf("Expect tree loaded without data")
+	}
+
+	/* Truncated input */
+	_, err = l.ReadFrom(bytes.NewReader(b[:len(b) - 10]))
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("Expect format error with truncated input, got %v", err)
+	}
+
+	/* Empty tree */
+	buf.Reset()
+	r = NewTree[string]()
+	r.WriteTo(&buf)
+	_, err = l.ReadFrom(&buf)
+	if err != nil || l.Len() != 0 {
+		t.Errorf("Expect empty tree, got %v", err)
+	}
+}
+
+func TestCodecCorrupted(t *testing.T) {
+	var r *Tree[string]
+	var l *Tree[string]
+	var buf bytes.Buffer
+	var b []byte
+	var c []byte
+	var s string
+	var offset int
+	var err error
+
+	r = NewTree[string]()
+	r.SetCodec(string_codec{})
+	for _, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16"} {
+		r.IPv4Insert(ipv4net(s), s)
+	}
+	r.WriteTo(&buf)
+	b = buf.Bytes()
+
+	/* The header is 24 bytes, the root node is the leaf 10.0.0.0/8:
+	 * kind at 24, start at 25, end at 27, key length at 29, key at 31.
+	 */
+	l = NewTree[string]()
+	l.SetCodec(string_codec{})
+	for _, offset = range []int{0, 29, 30, 31, len(b) - 1} {
+		c = append([]byte{}, b...)
+		c[offset] ^= 0xff
+		_, err = l.ReadFrom(bytes.NewReader(c))
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("Expect format error with byte %d corrupted, got %v", offset, err)
+		}
+		if l.Len() != 0 || l.First() != nil {
+			t.Errorf("Expect empty tree after error")
+		}
+	}
+
+	/* Key length exceeding the remaining bytes */
+	c = append([]byte{}, b...)
+	binary.BigEndian.PutUint16(c[29:], 0x0fff)
+	_, err = l.ReadFrom(bytes.NewReader(c))
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("Expect format error with long key, got %v", err)
+	}
+
+	/* A crafted internal node without bit and a valid checksum. The
+	 * second node is the internal node parent of 10.1.0.0/16 and
+	 * 10.2.0.0/16, its end is set before its start.
+	 */
+	c = append([]byte{}, b...)
+	offset = 24 + 7 + 4 + 4 + len("10.0.0.0/8")
+	if c[offset] != codec_kind_left | codec_kind_right || binary.BigEndian.Uint16(c[offset + 1:]) != 8 {
+		t.Fatalf("Unexpected node layout")
+	}
+	binary.BigEndian.PutUint16(c[offset + 3:], 7)
+	binary.BigEndian.PutUint32(c[len(c) - 4:], crc32.ChecksumIEEE(c[:len(c) - 4]))
+	_, err = l.ReadFrom(bytes.NewReader(c))
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("Expect format error with node without bit, got %v", err)
+	}
+}
@@ -0,0 +1,689 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bufio"
+import "encoding/binary"
+import "fmt"
+import "io"
+import "net/netip"
+import "os"
+
+/* Compiled tree format, all integer are little endian. The file
+ * is designed to be mapped in memory and queried in place.
+ *
+ *   header (64 bytes):
+ *      0  magic     [4]byte "GRDC"
+ *      4  version   uint16
+ *      6  flags     uint16
+ *      8  length    uint64  number of leaf
+ *     16  count     uint32  number of node records
+ *     20  reserved  uint32
+ *     24  nodes     uint64  offset of node records
+ *     32  keys      uint64  offset of keys area
+ *     40  values    uint64  offset of data area
+ *     48  size      uint64  file size
+ *     56  reserved  uint64
+ *
+ *   node record (40 bytes). Records are stored in tree order, the
+ *   reference of a node is its index + 1, so like the tree
+ *   references, 0 is the null reference.
+ *      0  left      uint32
+ *      4  right     uint32
+ *      8  parent    uint32
+ *     12  start     int16
+ *     14  end       int16
+ *     16  key off   uint32  offset in keys area
+ *     20  key len   uint16
+ *     22  flags     uint16  bit 0: leaf
+ *     24  data off  uint64  offset in data area
+ *     32  data len  uint32
+ *     36  reserved  uint32
+ */
+
+const compiled_magic = "GRDC"
+const compiled_version = 1
+const compiled_header_sz = 64
+const compiled_node_sz = 40
+const compiled_flag_leaf = 0x0001
+
+type compiled_builder[V any] struct {
+	r *Tree[V]
+	nodes []byte
+	keys []byte
+	values []byte
+	err error
+}
+
+/* Append node and its children in tree order, return its reference */
+func (b *compiled_builder[V])add(ref uint32, parent uint32)(uint32) {
+	var n *node
+	var rec []byte
+	var index uint32
+	var data []byte
+	var left uint32
+	var right uint32
+
+	n = b.r.r2n(ref)
+	index = uint32(len(b.nodes) / compiled_node_sz) + 1
+	b.nodes = append(b.nodes, make([]byte, compiled_node_sz)...)
+
+	if uint64(len(b.keys)) + uint64(len(n.Bytes)) > 0xffffffff {
+		b.err = fmt.Errorf("radix: keys area exceed 4GB")
+		return null
+	}
+	if is_leaf(ref) && b.r.codec != nil {
+		data, b.err = b.r.codec.Marshal(n2N[V](n).Data)
+		if b.err != nil {
+			return null
+		}
+	}
+
+	rec = b.nodes[(index - 1) * compiled_node_sz:index * compiled_node_sz]
+	binary.LittleEndian.PutUint32(rec[8:], parent)
+	binary.LittleEndian.PutUint16(rec[12:], uint16(n.Start))
+	binary.LittleEndian.PutUint16(rec[14:], uint16(n.End))
+	binary.LittleEndian.PutUint32(rec[16:], uint32(len(b.keys)))
+	binary.LittleEndian.PutUint16(rec[20:], uint16(len(n.Bytes)))
+	if is_leaf(ref) {
+		binary.LittleEndian.PutUint16(rec[22:], compiled_flag_leaf)
+	}
+	binary.LittleEndian.PutUint64(rec[24:], uint64(len(b.values)))
+	binary.LittleEndian.PutUint32(rec[32:], uint32(len(data)))
+	b.keys = append(b.keys, n.Bytes...)
+	b.values = append(b.values, data...)
+
+	/* Children, the nodes slice could be reallocated */
+	if n.Left != null {
+		left = b.add(n.Left, index)
+	}
+	if n.Right != null {
+		right = b.add(n.Right, index)
+	}
+	rec = b.nodes[(index - 1) * compiled_node_sz:index * compiled_node_sz]
+	binary.LittleEndian.PutUint32(rec[0:], left)
+	binary.LittleEndian.PutUint32(rec[4:], right)
+	return index
+}
+
+// Compile write the tree in w as a flat compiled tree which could be
+// mapped in memory with OpenCompiled and queried in place. The leaf
+// data are encoded with the codec defined by SetCodec, without codec
+// the compiled leaves have no data.
+func (r *Tree[V])Compile(w io.Writer)(error) {
+	var b *compiled_builder[V]
+	var hdr [compiled_header_sz]byte
+	var bw *bufio.Writer
+	var d []byte
+	var err error
+
+	b = &compiled_builder[V]{r: r}
+	if r.Node != null {
+		b.add(r.Node, null)
+		if b.err != nil {
+			return b.err
+		}
+	}
+
+	copy(hdr[0:], compiled_magic)
+	binary.LittleEndian.PutUint16(hdr[4:], compiled_version)
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(r.length))
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(b.nodes) / compiled_node_sz))
+	binary.LittleEndian.PutUint64(hdr[24:], compiled_header_sz)
+	binary.LittleEndian.PutUint64(hdr[32:], uint64(compiled_header_sz + len(b.nodes)))
+	binary.LittleEndian.PutUint64(hdr[40:], uint64(compiled_header_sz + len(b.nodes) + len(b.keys)))
+	binary.LittleEndian.PutUint64(hdr[48:], uint64(compiled_header_sz + len(b.nodes) + len(b.keys) + len(b.values)))
+
+	bw = bufio.NewWriter(w)
+	for _, d = range [][]byte{hdr[:], b.nodes, b.keys, b.values} {
+		_, err = bw.Write(d)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Compiled is a read-only tree produced by Tree.Compile. The tree is
+// queried in place in the mapped file, so it does not use the Go heap
+// and many processes could share the same file using the page cache.
+type Compiled struct {
+	data []byte
+	nodes []byte
+	keys []byte
+	values []byte
+	count uint32
+	length int
+	unmap func([]byte)(error)
+}
+
+// CompiledLeaf is a leaf of a compiled tree. The zero value is not a
+// valid leaf.
+type CompiledLeaf struct {
+	c *Compiled
+	ref uint32
+}
+
+// OpenCompiled map the compiled tree file in memory. The Compiled
+// must be closed with Close.
+func OpenCompiled(path string)(*Compiled, error) {
+	var f *os.File
+	var st os.FileInfo
+	var data []byte
+	var c *Compiled
+	var err error
+
+	f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	st, err = f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() < compiled_header_sz {
+		return nil, fmt.Errorf("%w: compiled tree too short", ErrFormat)
+	}
+
+	data, err = mmap_file(f, int(st.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err = NewCompiled(data)
+	if err != nil {
+		munmap_file(data)
+		return nil, err
+	}
+	c.unmap = munmap_file
+	return c, nil
+}
+
+// NewCompiled use the compiled tree stored in data. data must not be
+// modified while the Compiled is in use. Only the header is checked, so
+// the open does not read the node records: a truncated tree returns an
+// error wrapping ErrFormat, and the node records are checked when they
+// are browsed, a corrupted record is ignored. Verify checks all the
+// records.
+func NewCompiled(data []byte)(*Compiled, error) {
+	var c *Compiled
+	var nodes uint64
+	var keys uint64
+	var values uint64
+	var size uint64
+
+	if len(data) < compiled_header_sz || string(data[0:4]) != compiled_magic {
+		return nil, fmt.Errorf("%w: bad magic", ErrFormat)
+	}
+	if binary.LittleEndian.Uint16(data[4:]) != compiled_version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrFormat, binary.LittleEndian.Uint16(data[4:]))
+	}
+
+	c = &Compiled{}
+	c.length = int(binary.LittleEndian.Uint64(data[8:]))
+	c.count = binary.LittleEndian.Uint32(data[16:])
+	nodes = binary.LittleEndian.Uint64(data[24:])
+	keys = binary.LittleEndian.Uint64(data[32:])
+	values = binary.LittleEndian.Uint64(data[40:])
+	size = binary.LittleEndian.Uint64(data[48:])
+	if size != uint64(len(data)) || nodes > keys || keys > values || values > size ||
+	   keys - nodes != uint64(c.count) * compiled_node_sz {
+		return nil, fmt.Errorf("%w: inconsistent header", ErrFormat)
+	}
+	c.data = data
+	c.nodes = data[nodes:keys]
+	c.keys = data[keys:values]
+	c.values = data[values:size]
+	return c, nil
+}
+
+// Verify check all the node records: the keys and the data are in the
+// file, the start and end bits, the parent and children references and
+// the number of leaf. Return nil if the tree is valid, otherwise return
+// an error wrapping ErrFormat. The records are in tree order, so the
+// children references are after the node reference.
+func (c *Compiled)Verify()(error) {
+	var ref uint32
+	var rec []byte
+	var child uint32
+	var leaves int
+	var end int16
+	var koff uint64
+	var doff uint64
+
+	for ref = 1; ref <= c.count; ref++ {
+		rec = c.rec(ref)
+		end = c.end(ref)
+		if c.start(ref) < 0 || end < c.start(ref) - 1 {
+			return fmt.Errorf("%w: node %d has bad start or end", ErrFormat, ref)
+		}
+		koff = uint64(binary.LittleEndian.Uint32(rec[16:])) + uint64(binary.LittleEndian.Uint16(rec[20:]))
+		if koff > uint64(len(c.keys)) {
+			return fmt.Errorf("%w: node %d key out of file", ErrFormat, ref)
+		}
+		if int(end) >= int(binary.LittleEndian.Uint16(rec[20:])) * 8 {
+			return fmt.Errorf("%w: node %d key shorter than its end", ErrFormat, ref)
+		}
+		if (ref == 1) != (c.raw_parent(ref) == null) || c.raw_parent(ref) >= ref {
+			return fmt.Errorf("%w: node %d has bad parent", ErrFormat, ref)
+		}
+		for _, child = range []uint32{c.raw_left(ref), c.raw_right(ref)} {
+			if child == null {
+				continue
+			}
+			if child <= ref || child > c.count || c.raw_parent(child) != ref {
+				return fmt.Errorf("%w: node %d has bad child %d", ErrFormat, ref, child)
+			}
+			if c.start(child) != end + 1 {
+				return fmt.Errorf("%w: node %d start does not follow parent", ErrFormat, child)
+			}
+		}
+		if c.is_leaf(ref) {
+			leaves++
+			doff = binary.LittleEndian.Uint64(rec[24:])
+			if doff > uint64(len(c.values)) || uint64(binary.LittleEndian.Uint32(rec[32:])) > uint64(len(c.values)) - doff {
+				return fmt.Errorf("%w: node %d data out of file", ErrFormat, ref)
+			}
+		}
+	}
+	if leaves != c.length {
+		return fmt.Errorf("%w: %d leaves found, expect %d", ErrFormat, leaves, c.length)
+	}
+	return nil
+}
+
+// Close release the memory mapping. The Compiled and its leaves must
+// not be used after Close.
+func (c *Compiled)Close()(error) {
+	var err error
+
+	if c.unmap != nil {
+		err = c.unmap(c.data)
+		c.unmap = nil
+	}
+	c.data = nil
+	c.nodes = nil
+	c.keys = nil
+	c.values = nil
+	c.count = 0
+	return err
+}
+
+// Len return the number of leaf in the tree.
+func (c *Compiled)Len()(int) {
+	return c.length
+}
+
+func (c *Compiled)rec(ref uint32)([]byte) {
+	return c.nodes[(ref - 1) * compiled_node_sz:ref * compiled_node_sz]
+}
+
+func (c *Compiled)raw_left(ref uint32)(uint32) {
+	return binary.LittleEndian.Uint32(c.rec(ref)[0:])
+}
+
+func (c *Compiled)raw_right(ref uint32)(uint32) {
+	return binary.LittleEndian.Uint32(c.rec(ref)[4:])
+}
+
+func (c *Compiled)raw_parent(ref uint32)(uint32) {
+	return binary.LittleEndian.Uint32(c.rec(ref)[8:])
+}
+
+/* Check the record ref is in the file, its key and its data are in the
+ * file and its end bit is in the key. The records are checked when they
+ * are browsed, so a corrupted file is never read out of the mapping.
+ */
+func (c *Compiled)valid(ref uint32)(bool) {
+	var rec []byte
+	var start int16
+	var end int16
+	var klen uint64
+	var doff uint64
+
+	if ref == null || ref > c.count {
+		return false
+	}
+	rec = c.rec(ref)
+	start = c.start(ref)
+	end = c.end(ref)
+	klen = uint64(binary.LittleEndian.Uint16(rec[20:]))
+	if start < 0 || end < start - 1 || uint64(int64(end) + 1) > klen * 8 {
+		return false
+	}
+	if uint64(binary.LittleEndian.Uint32(rec[16:])) + klen > uint64(len(c.keys)) {
+		return false
+	}
+	if c.is_leaf(ref) {
+		doff = binary.LittleEndian.Uint64(rec[24:])
+		if doff > uint64(len(c.values)) || uint64(binary.LittleEndian.Uint32(rec[32:])) > uint64(len(c.values)) - doff {
+			return false
+		}
+	}
+	return true
+}
+
+/* Return the child if it is a valid record after ref with ref as
+ * parent, otherwise return null. The browsing always goes down to
+ * the next records, so it ends even if the file is corrupted.
+ */
+func (c *Compiled)child(ref uint32, child uint32)(uint32) {
+	if child <= ref || !c.valid(child) || c.raw_parent(child) != ref {
+		return null
+	}
+	return child
+}
+
+func (c *Compiled)left(ref uint32)(uint32) {
+	return c.child(ref, c.raw_left(ref))
+}
+
+func (c *Compiled)right(ref uint32)(uint32) {
+	return c.child(ref, c.raw_right(ref))
+}
+
+/* Return the parent if it is a valid record before ref, otherwise
+ * return null.
+ */
+func (c *Compiled)parent(ref uint32)(uint32) {
+	var p uint32
+
+	p = c.raw_parent(ref)
+	if p >= ref || !c.valid(p) {
+		return null
+	}
+	return p
+}
+
+func (c *Compiled)start(ref uint32)(int16) {
+	return int16(binary.LittleEndian.Uint16(c.rec(ref)[12:]))
+}
+
+func (c *Compiled)end(ref uint32)(int16) {
+	return int16(binary.LittleEndian.Uint16(c.rec(ref)[14:]))
+}
+
+func (c *Compiled)key(ref uint32)([]byte) {
+	var rec []byte
+	var off uint32
+
+	rec = c.rec(ref)
+	off = binary.LittleEndian.Uint32(rec[16:])
+	return c.keys[off:off + uint32(binary.LittleEndian.Uint16(rec[20:]))]
+}
+
+func (c *Compiled)is_leaf(ref uint32)(bool) {
+	return binary.LittleEndian.Uint16(c.rec(ref)[22:]) & compiled_flag_leaf != 0
+}
+
+func (c *Compiled)root()(uint32) {
+	if !c.valid(1) {
+		return null
+	}
+	return 1
+}
+
+/* Same algorithm than lookup_longuest_last_node */
+func (c *Compiled)lookup_last_node(data []byte, length int16)(uint32) {
+	var ref uint32
+	var end int16
+	var next uint32
+
+	length--
+	ref = c.root()
+	for {
+		if ref == null || length <= c.end(ref) {
+			return ref
+		}
+		end = c.end(ref)
+		if end != -1 && !bitcmp(c.key(ref), data, c.start(ref), end) {
+			return ref
+		}
+		end++
+		if data[end / 8] & (0x80 >> (end % 8)) != 0 {
+			next = c.right(ref)
+		} else {
+			next = c.left(ref)
+		}
+		if next == null {
+			return ref
+		}
+		ref = next
+	}
+}
+
+// LookupLonguestPath take a key/length prefix, return the list of all leaf
+// matching the prefix. If none match, return empty list
+func (c *Compiled)LookupLonguestPath(data *[]byte, length int16)([]CompiledLeaf) {
+	var path []CompiledLeaf
+	var ref uint32
+	var end int16
+
+	length--
+	path = make([]CompiledLeaf, 0)
+	ref = c.root()
+	for ref != null {
+		end = c.end(ref)
+		if length < end || (end != -1 && !bitcmp(c.key(ref), *data, c.start(ref), end)) {
+			return path
+		}
+		if c.is_leaf(ref) {
+			path = append(path, CompiledLeaf{c: c, ref: ref})
+		}
+		if length <= end {
+			return path
+		}
+		end++
+		if (*data)[end / 8] & (0x80 >> (end % 8)) != 0 {
+			ref = c.right(ref)
+		} else {
+			ref = c.left(ref)
+		}
+	}
+	return path
+}
+
+// LookupLonguest get a key/length prefix and return the leaf which match the
+// longest part of the prefix. Return false if none match.
+func (c *Compiled)LookupLonguest(data *[]byte, length int16)(CompiledLeaf, bool) {
+	var last CompiledLeaf
+	var ref uint32
+	var end int16
+
+	length--
+	ref = c.root()
+	for ref != null {
+		end = c.end(ref)
+		if length < end || (end != -1 && !bitcmp(c.key(ref), *data, c.start(ref), end)) {
+			break
+		}
+		if c.is_leaf(ref) {
+			last = CompiledLeaf{c: c, ref: ref}
+		}
+		if length <= end {
+			break
+		}
+		end++
+		if (*data)[end / 8] & (0x80 >> (end % 8)) != 0 {
+			ref = c.right(ref)
+		} else {
+			ref = c.left(ref)
+		}
+	}
+	return last, last.ref != null
+}
+
+// Get gets a key/length prefix and return exact match of the prefix. Return
+// false if the prefix does not exists.
+func (c *Compiled)Get(data *[]byte, length int16)(CompiledLeaf, bool) {
+	var l CompiledLeaf
+	var ok bool
+
+	l, ok = c.LookupLonguest(data, length)
+	if !ok || l.Length() != length {
+		return CompiledLeaf{}, false
+	}
+	return l, true
+}
+
+// AddrLookupLonguest get a IPv4 or IPv6 address and return the leaf which
+// match the longest part of the address. Return false if none match.
+func (c *Compiled)AddrLookupLonguest(addr netip.Addr)(CompiledLeaf, bool) {
+	var buf [16]byte
+	var key []byte
+
+	if !addr.IsValid() {
+		return CompiledLeaf{}, false
+	}
+	key = addr_to_key(addr, &buf)
+	return c.LookupLonguest(&key, int16(addr.BitLen()))
+}
+
+// PrefixGet gets a IPv4 or IPv6 prefix and return exact match of the
+// prefix. Return false if the prefix does not exists.
+func (c *Compiled)PrefixGet(prefix netip.Prefix)(CompiledLeaf, bool) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	key, length = prefix_to_key(prefix, &buf)
+	if length == 0 {
+		return CompiledLeaf{}, false
+	}
+	return c.Get(&key, length)
+}
+
+/* Same algorithm than Tree.next */
+func (c *Compiled)next(ref uint32)(uint32) {
+	var prev uint32
+	var cur uint32
+
+	prev = null
+	cur = ref
+	for {
+		if prev == c.parent(cur) || prev == null {
+			prev = cur
+			if c.left(cur) != null {
+				cur = c.left(cur)
+			} else if c.right(cur) != null {
+				cur = c.right(cur)
+			} else if c.parent(cur) != null {
+				cur = c.parent(cur)
+			}
+		} else if prev == c.left(cur) {
+			prev = cur
+			if c.right(cur) != null {
+				cur = c.right(cur)
+			} else if c.parent(cur) != null {
+				cur = c.parent(cur)
+			}
+		} else if prev == c.right(cur) {
+			prev = cur
+			if c.parent(cur) != null {
+				cur = c.parent(cur)
+			}
+		} else {
+			/* The parent does not reference the node, the
+			 * record is corrupted.
+			 */
+			return null
+		}
+
+		if cur == prev {
+			return null
+		}
+
+		if c.is_leaf(cur) && prev == c.parent(cur) {
+			return cur
+		}
+	}
+}
+
+// CompiledIter is a struct for managing iteration on a compiled tree.
+type CompiledIter struct {
+	c *Compiled
+	ref uint32
+	next_ref uint32
+	key []byte
+	length int16
+}
+
+// NewIter return struct CompiledIter for browsing all leaves there children
+// match the given key/length prefix. If length is 0, key could be nil and
+// all the leaves are browsed.
+func (c *Compiled)NewIter(key *[]byte, length int16)(*CompiledIter) {
+	var i *CompiledIter
+
+	i = &CompiledIter{c: c, length: length}
+	if length == 0 {
+		i.next_ref = c.root()
+	} else {
+		i.key = *key
+		i.next_ref = c.lookup_last_node(*key, length)
+		if i.next_ref != null && !is_children_of(c.key(i.next_ref), *key, c.end(i.next_ref), length - 1) {
+			i.next_ref = null
+		}
+	}
+	if i.next_ref != null && !c.is_leaf(i.next_ref) {
+		i.set_next()
+	}
+	return i
+}
+
+func (i *CompiledIter)set_next() {
+	if i.next_ref == null {
+		return
+	}
+	i.next_ref = i.c.next(i.next_ref)
+	if i.next_ref != null && i.length > 0 && !is_children_of(i.c.key(i.next_ref), i.key, i.c.end(i.next_ref), i.length - 1) {
+		i.next_ref = null
+	}
+}
+
+// Next return true if there next leaf avalaible. This function
+// also perform lookup for the next leaf.
+func (i *CompiledIter)Next()(bool) {
+	i.ref = i.next_ref
+	i.set_next()
+	return i.ref != null
+}
+
+// Get return the leaf.
+func (i *CompiledIter)Get()(CompiledLeaf) {
+	return CompiledLeaf{c: i.c, ref: i.ref}
+}
+
+// Key return the key of the leaf. The returned slice is in the mapped
+// file, it must not be modified and not used after Close.
+func (l CompiledLeaf)Key()([]byte) {
+	return l.c.key(l.ref)
+}
+
+// Length return the length of the leaf prefix in bits.
+func (l CompiledLeaf)Length()(int16) {
+	return l.c.end(l.ref) + 1
+}
+
+// Data return the data of the leaf encoded by the codec. The returned
+// slice is in the mapped file, it must not be modified and not used
+// after Close.
+func (l CompiledLeaf)Data()([]byte) {
+	var rec []byte
+	var off uint64
+
+	rec = l.c.rec(l.ref)
+	off = binary.LittleEndian.Uint64(rec[24:])
+	return l.c.values[off:off + uint64(binary.LittleEndian.Uint32(rec[32:]))]
+}
+
+// Prefix convert leaf key/length prefix to netip.Prefix. Return an
+// invalid prefix if the key is not a network.
+func (l CompiledLeaf)Prefix()(netip.Prefix) {
+	var n Node
+
+	n.node.Bytes = string(l.Key())
+	n.node.End = l.c.end(l.ref)
+	return n.Prefix()
+}
@@ -0,0 +1,247 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "math/rand"
+import "net/netip"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestCompiled(t *testing.T) {
+	var r *Tree[string]
+	var c *Compiled
+	var buf bytes.Buffer
+	var f *os.File
+	var path string
+	var p netip.Prefix
+	var a netip.Addr
+	var n *Leaf[string]
+	var l CompiledLeaf
+	var ok bool
+	var np []*Leaf[string]
+	var cp []CompiledLeaf
+	var it *Iterator[string]
+	var cit *CompiledIter
+	var key []byte
+	var err error
+	var i int
+	var j int
+
+	r = NewTree[string]()
+	r.SetCodec(string_codec{})
+	for i = 0; i < 10000; i++ {
+		p = netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), 0}), 8 + rand.Intn(17)).Masked()
+		r.PrefixInsert(p, p.String())
+	}
+
+	err = r.Compile(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	path = filepath.Join(t.TempDir(), "tree.bin")
+	f, err = os.Create(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	f.Write(buf.Bytes())
+	f.Close()
+
+	c, err = OpenCompiled(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer c.Close()
+	if c.Len() != r.Len() {
+		t.Fatalf("Expect %d leaves, got %d", r.Len(), c.Len())
+	}
+
+	/* Compare lookups */
+	for i = 0; i < 10000; i++ {
+		a = netip.AddrFrom4([4]byte{byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256))})
+		n = r.AddrLookupLonguest(a)
+		l, ok = c.AddrLookupLonguest(a)
+		if (n == nil) != !ok {
+			t.Fatalf("Lookup mismatch for %s", a)
+		}
+		if n != nil && (string(l.Data()) != n.Data || l.Prefix() != n.Prefix()) {
+			t.Fatalf("Lookup mismatch for %s: expect %s, got %s", a, n.Data, string(l.Data()))
+		}
+
+		p = netip.PrefixFrom(a, 8 + rand.Intn(17)).Masked()
+		n = r.PrefixGet(p)
+		l, ok = c.PrefixGet(p)
+		if (n == nil) != !ok {
+			t.Fatalf("Get mismatch for %s", p)
+		}
+
+		key = a.AsSlice()
+		np = r.LookupLonguestPath(&key, 32)
+		cp = c.LookupLonguestPath(&key, 32)
+		if len(np) != len(cp) {
+			t.Fatalf("Path mismatch for %s: expect %d, got %d", a, len(np), len(cp))
+		}
+		for j = range np {
+			if np[j].Prefix() != cp[j].Prefix() {
+				t.Fatalf("Path mismatch for %s", a)
+			}
+		}
+	}
+
+	/* Compare iterations */
+	for _, p = range []netip.Prefix{netip.Prefix{}, netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.0/16")} {
+		key = p.Addr().AsSlice()
+		if p.IsValid() {
+			it = r.NewIter(&key, int16(p.Bits()))
+			cit = c.NewIter(&key, int16(p.Bits()))
+		} else {
+			it = r.NewIter(nil, 0)
+			cit = c.NewIter(nil, 0)
+		}
+		for it.Next() {
+			if !cit.Next() {
+				t.Fatalf("Iteration on %s stops early", p)
+			}
+			if it.Get().Prefix() != cit.Get().Prefix() || it.Get().Data != string(cit.Get().Data()) {
+				t.Fatalf("Iteration mismatch on %s", p)
+			}
+		}
+		if cit.Next() {
+			t.Fatalf("Iteration on %s continue", p)
+		}
+	}
+}
+
+func TestCompiledEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	var c *Compiled
+	var key []byte
+	var ok bool
+	var err error
+
+	err = NewRadix().Compile(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	c, err = NewCompiled(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	key = []byte{1, 2, 3, 4}
+	_, ok = c.LookupLonguest(&key, 32)
+	if ok {
+		t.Errorf("Expect no match")
+	}
+	if c.NewIter(nil, 0).Next() {
+		t.Errorf("Expect empty iteration")
+	}
+
+	_, err = NewCompiled(buf.Bytes()[:20])
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("Expect ErrFormat, got %v", err)
+	}
+}
+
+func TestCompiledCorrupted(t *testing.T) {
+	var r *Tree[string]
+	var buf bytes.Buffer
+	var b []byte
+	var c []byte
+	var corrupted [][]byte
+	var cc *Compiled
+	var it *CompiledIter
+	var key []byte
+	var s string
+	var path string
+	var count int
+	var i int
+	var err error
+
+	r = NewTree[string]()
+	r.SetCodec(string_codec{})
+	for _, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16", "192.168.0.0/16"} {
+		r.IPv4Insert(ipv4net(s), s)
+	}
+	err = r.Compile(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	b = buf.Bytes()
+
+	/* Truncated data area with a consistent header */
+	c = append([]byte{}, b[:len(b) - 5]...)
+	binary.LittleEndian.PutUint64(c[48:], uint64(len(c)))
+	corrupted = append(corrupted, c)
+
+	/* Child reference out of the records */
+	c = append([]byte{}, b...)
+	binary.LittleEndian.PutUint32(c[compiled_header_sz:], 1000)
+	corrupted = append(corrupted, c)
+
+	/* Child reference to a previous record, which makes a loop */
+	c = append([]byte{}, b...)
+	binary.LittleEndian.PutUint32(c[compiled_header_sz + compiled_node_sz:], 1)
+	corrupted = append(corrupted, c)
+
+	/* Parent reference to a later record */
+	c = append([]byte{}, b...)
+	binary.LittleEndian.PutUint32(c[compiled_header_sz + compiled_node_sz + 8:], 3)
+	corrupted = append(corrupted, c)
+
+	/* Key out of the keys area */
+	c = append([]byte{}, b...)
+	binary.LittleEndian.PutUint32(c[compiled_header_sz + compiled_node_sz + 16:], 0xffffff00)
+	corrupted = append(corrupted, c)
+
+	/* End bit out of the key */
+	c = append([]byte{}, b...)
+	binary.LittleEndian.PutUint16(c[compiled_header_sz + compiled_node_sz + 14:], 0x7fff)
+	corrupted = append(corrupted, c)
+
+	/* Truncated file */
+	path = filepath.Join(t.TempDir(), "truncated.grdc")
+	err = os.WriteFile(path, b[:len(b) - 5], 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	_, err = OpenCompiled(path)
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("Expect ErrFormat, got %v", err)
+	}
+
+	/* Corrupted records are found by Verify. The queries ignore them,
+	 * they never panic and the browsing ends.
+	 */
+	for i, c = range corrupted {
+		cc, err = NewCompiled(c)
+		if err != nil {
+			t.Fatalf("Unexpected error %s on corruption %d", err, i)
+		}
+		if !errors.Is(cc.Verify(), ErrFormat) {
+			t.Errorf("Expect ErrFormat on corruption %d, got %v", i, cc.Verify())
+		}
+		for _, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16", "192.168.0.0/16"} {
+			key = ipv4net(s).IP
+			cc.LookupLonguest(&key, 32)
+			cc.LookupLonguestPath(&key, 32)
+		}
+		it = cc.NewIter(nil, 0)
+		for count = 0; it.Next(); count++ {
+			it.Get().Key()
+			it.Get().Data()
+			it.Get().Prefix()
+			if count > 4 {
+				t.Fatalf("Iteration does not end on corruption %d", i)
+			}
+		}
+	}
+
+	/* The original is valid */
+	cc, err = NewCompiled(b)
+	if err != nil || cc.Verify() != nil {
+		t.Errorf("Unexpected error %v %v", err, cc.Verify())
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+/* Return the subtree count slot of the reference */
+func (r *Tree[V])count_ref(ref uint32)(*uint32) {
+	if is_leaf(ref) {
+		return &r.leaf.pool[(ref >> 16) & 0x7fff].count[ref & 0xffff]
+	}
+	return &r.node.pool[ref >> 16].count[ref & 0xffff]
+}
+
+/* Return the number of leaves of the subtree ref */
+func (r *Tree[V])count(ref uint32)(int) {
+	if ref == null {
+		return 0
+	}
+	return int(*r.count_ref(ref))
+}
+
+/* Compute the subtree count of ref from its children */
+func (r *Tree[V])count_set(ref uint32) {
+	var n *node
+	var count uint32
+
+	n = r.r2n(ref)
+	if is_leaf(ref) {
+		count = 1
+	}
+	if n.Left != null {
+		count += *r.count_ref(n.Left)
+	}
+	if n.Right != null {
+		count += *r.count_ref(n.Right)
+	}
+	*r.count_ref(ref) = count
+}
+
+/* Update the subtree counts from ref up to the root */
+func (r *Tree[V])count_fix(ref uint32) {
+	for ref != null {
+		r.count_set(ref)
+		ref = r.r2n(ref).Parent
+	}
+}
+
+/* Compute the subtree counts of all the nodes of the subtree ref */
+func (r *Tree[V])count_build(ref uint32) {
+	var n *node
+
+	if ref == null {
+		return
+	}
+	n = r.r2n(ref)
+	r.count_build(n.Left)
+	r.count_build(n.Right)
+	r.count_set(ref)
+}
+
+// EnableCounts maintains in each node the number of leaves of its
+// subtree. This cost 4 bytes per node and leaf and a browsing up to the
+// root on each insert or delete, but CountPrefix, Rank and Select run
+// in O(depth) in place of browsing the leaves. The counts are computed
+// for the existing leaves.
+func (r *Tree[V])EnableCounts() {
+	var cn *node_chunk
+	var cl *leaf_chunk[V]
+
+	if r.counted {
+		return
+	}
+	if r.cow != nil {
+		r.cow_all()
+	}
+	r.counted = true
+	for _, cn = range r.node.pool {
+		cn.count = make([]uint32, len(cn.nodes))
+	}
+	for _, cl = range r.leaf.pool {
+		cl.count = make([]uint32, len(cl.nodes))
+	}
+	r.count_build(r.Node)
+}
+
+// DisableCounts stop maintaining the subtree counts and release their
+// memory.
+func (r *Tree[V])DisableCounts() {
+	var cn *node_chunk
+	var cl *leaf_chunk[V]
+
+	if r.cow != nil {
+		r.cow_all()
+	}
+	r.counted = false
+	for _, cn = range r.node.pool {
+		cn.count = nil
+	}
+	for _, cl = range r.leaf.pool {
+		cl.count = nil
+	}
+}
+
+// CountPrefix return the number of leaves matching the key/length prefix,
+// including the leaf of the prefix itself. A length of 0 count all the
+// leaves. If the counts are not enabled, the leaves are browsed.
+func (r *Tree[V])CountPrefix(key *[]byte, length int16)(int) {
+	var ref uint32
+	var i Iterator[V]
+	var count int
+
+	if length == 0 {
+		return r.length
+	}
+	if !r.counted {
+		i.init(r, key, length)
+		for i.Next() {
+			count++
+		}
+		return count
+	}
+	ref = r.prefix_root(*key, length)
+	return r.count(ref)
+}
+
+// Rank return the position of the leaf in tree order, the first leaf
+// has position 0. If the counts are not enabled, the leaves before n are
+// browsed.
+func (r *Tree[V])Rank(n *Leaf[V])(int) {
+	var ref uint32
+	var parent uint32
+	var p *node
+	var rank int
+	var l *Leaf[V]
+
+	n = n2N[V](r.fresh(&n.node))
+	if !r.counted {
+		for l = r.First(); l != nil && l != n; l = r.Next(l) {
+			rank++
+		}
+		return rank
+	}
+
+	/* Count the leaves before each node of the path up to the root:
+	 * the leaf parents and the left subtrees of the right children.
+	 */
+	ref = r.n2r(&n.node)
+	for {
+		parent = r.r2n(ref).Parent
+		if parent == null {
+			return rank
+		}
+		p = r.r2n(parent)
+		if is_leaf(parent) {
+			rank++
+		}
+		if p.Right == ref {
+			rank += r.count(p.Left)
+		}
+		ref = parent
+	}
+}
+
+// Select return the leaf at position i in tree order, the first leaf
+// has position 0. Return nil if i is out of range. If the counts are not
+// enabled, the leaves before the position are browsed.
+func (r *Tree[V])Select(i int)(*Leaf[V]) {
+	var ref uint32
+	var n *node
+	var l *Leaf[V]
+
+	if i < 0 || i >= r.length {
+		return nil
+	}
+	if !r.counted {
+		for l = r.First(); l != nil && i > 0; l = r.Next(l) {
+			i--
+		}
+		return l
+	}
+
+	ref = r.Node
+	for ref != null {
+		n = r.r2n(ref)
+		if is_leaf(ref) {
+			if i == 0 {
+				return n2N[V](n)
+			}
+			i--
+		}
+		if i < r.count(n.Left) {
+			ref = n.Left
+		} else {
+			i -= r.count(n.Left)
+			ref = n.Right
+		}
+	}
+	return nil
+}
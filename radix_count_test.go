@@ -0,0 +1,121 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "math/rand"
+import "net"
+import "testing"
+
+/* Check the counts against the browsing of the leaves */
+func count_check(t *testing.T, r *Tree[int]) {
+	var n *Leaf[int]
+	var i int
+	var err error
+
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	for n = range r.All() {
+		if r.Rank(n) != i {
+			t.Fatalf("Expect rank %d, got %d", i, r.Rank(n))
+		}
+		if r.Select(i) != n {
+			t.Fatalf("Unexpected leaf at position %d", i)
+		}
+		i++
+	}
+	if r.Select(i) != nil || r.Select(-1) != nil {
+		t.Fatalf("Unexpected leaf out of range")
+	}
+}
+
+func TestCounts(t *testing.T) {
+	var r *Tree[int]
+	var c *Tree[int]
+	var buf bytes.Buffer
+	var nw *net.IPNet
+	var n *Leaf[int]
+	var key []byte
+	var length int16
+	var count int
+	var i int
+	var err error
+
+	r = verify_random_tree()
+	r.EnableCounts()
+	count_check(t, r)
+
+	/* Insert and delete keep the counts */
+	for i = 0; i < 5000; i++ {
+		nw = &net.IPNet{}
+		nw.IP = net.IPv4(byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), 0)
+		nw.Mask = net.CIDRMask(1 + rand.Intn(24), 32)
+		if rand.Intn(2) == 0 {
+			r.IPv4Insert(nw, i)
+		} else {
+			r.IPv4DeleteNetwork(nw)
+		}
+	}
+	for i = 0; i < 3000; i++ {
+		r.Delete(r.Select(rand.Intn(r.Len())))
+	}
+	count_check(t, r)
+
+	/* Count prefixes */
+	for i = 0; i < 1000; i++ {
+		key = []byte{byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256))}
+		length = int16(rand.Intn(20))
+		count = 0
+		for n = range r.Prefixed(&key, length) {
+			count++
+		}
+		if r.CountPrefix(&key, length) != count {
+			t.Fatalf("Expect %d leaves, got %d", count, r.CountPrefix(&key, length))
+		}
+	}
+
+	/* Compact, clone and codec keep the counts */
+	r.Compact()
+	count_check(t, r)
+	c = r.Clone(nil)
+	count_check(t, c)
+	_, err = r.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	c = NewTree[int]()
+	c.EnableCounts()
+	_, err = c.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	count_check(t, c)
+
+	/* Without counts */
+	r.DisableCounts()
+	if r.Verify() != nil {
+		t.Fatalf("Verify: %v", r.Verify())
+	}
+	i = 0
+	for n = range r.All() {
+		if i % 1000 == 0 && (r.Rank(n) != i || r.Select(i) != n) {
+			t.Fatalf("Unexpected rank or select at position %d", i)
+		}
+		i++
+	}
+	key = []byte{10, 0, 0, 0}
+	count = 0
+	for n = range r.Prefixed(&key, 8) {
+		count++
+	}
+	if r.CountPrefix(&key, 8) != count {
+		t.Errorf("Expect %d leaves, got %d", count, r.CountPrefix(&key, 8))
+	}
+	for n = range r.All() {
+		r.Delete(n)
+	}
+	r.EnableCounts()
+	count_check(t, r)
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+// DiffKind is the kind of difference reported by Diff.
+type DiffKind int
+
+const (
+	// DiffAdded is a prefix present only in the new tree.
+	DiffAdded DiffKind = iota
+	// DiffRemoved is a prefix present only in the old tree.
+	DiffRemoved
+	// DiffChanged is a prefix present in both trees with different data.
+	DiffChanged
+)
+
+/* Compare two prefixes according with the tree order. Return -1 if
+ * a is before b, 1 if a is after b and 0 if they are equal. The
+ * prefixes are ordered by bits, 0 before 1, and a prefix is before
+ * its children.
+ */
+func prefix_compare(a []byte, al int16, b []byte, bl int16)(int) {
+	var l int16
+	var bit int16
+
+	l = al
+	if bl < l {
+		l = bl
+	}
+	if l > 0 {
+		bit = bitlonguestmatch(a, b, 0, l - 1)
+		if bit != -1 {
+			if bitget(a, bit) == 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case al < bl:
+		return -1
+	case al > bl:
+		return 1
+	}
+	return 0
+}
+
+// Diff browse the old tree and the new tree cur in one pass and call fn
+// for each difference. For DiffAdded, old is nil and for DiffRemoved, cur
+// is nil.
+// The leaves with the same prefix are compared with equal, if equal is nil
+// only the added and removed prefixes are reported. The browsing stops if
+// fn returns false. The trees must not be modified during the diff.
+func Diff[V any](old *Tree[V], cur *Tree[V], equal func(a V, b V)(bool), fn func(kind DiffKind, old *Leaf[V], cur *Leaf[V])(bool)) {
+	var o *Leaf[V]
+	var n *Leaf[V]
+	var cmp int
+
+	o = old.First()
+	n = cur.First()
+	for o != nil || n != nil {
+		switch {
+		case o == nil:
+			cmp = 1
+		case n == nil:
+			cmp = -1
+		default:
+			cmp = prefix_compare([]byte(o.node.Bytes), o.node.End + 1, []byte(n.node.Bytes), n.node.End + 1)
+		}
+
+		switch {
+		case cmp < 0:
+			if !fn(DiffRemoved, o, nil) {
+				return
+			}
+			o = old.Next(o)
+		case cmp > 0:
+			if !fn(DiffAdded, nil, n) {
+				return
+			}
+			n = cur.Next(n)
+		default:
+			if equal != nil && !equal(o.Data, n.Data) && !fn(DiffChanged, o, n) {
+				return
+			}
+			o = old.Next(o)
+			n = cur.Next(n)
+		}
+	}
+}
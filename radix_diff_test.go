@@ -0,0 +1,96 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "math/rand"
+import "net/netip"
+import "testing"
+
+func TestDiff(t *testing.T) {
+	var a *Tree[int]
+	var b *Tree[int]
+	var ma map[netip.Prefix]int
+	var mb map[netip.Prefix]int
+	var p netip.Prefix
+	var v int
+	var ok bool
+	var count int
+	var last *Leaf[int]
+	var i int
+
+	a = NewTree[int]()
+	b = NewTree[int]()
+	ma = make(map[netip.Prefix]int)
+	mb = make(map[netip.Prefix]int)
+	for i = 0; i < 3000; i++ {
+		p = netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0}), 14 + rand.Intn(11)).Masked()
+		v = rand.Intn(3)
+		if rand.Intn(2) == 0 {
+			_, ok = a.PrefixInsert(p, v)
+			if ok {
+				ma[p] = v
+			}
+		} else {
+			_, ok = b.PrefixInsert(p, v)
+			if ok {
+				mb[p] = v
+			}
+		}
+	}
+
+	Diff(a, b, func(x int, y int)(bool) { return x == y }, func(kind DiffKind, o *Leaf[int], n *Leaf[int])(bool) {
+		var cur *Leaf[int]
+
+		switch kind {
+		case DiffRemoved:
+			cur = o
+			_, ok = mb[o.Prefix()]
+			if n != nil || ok {
+				t.Fatalf("Bad removed event for %s", o.Prefix())
+			}
+			delete(ma, o.Prefix())
+		case DiffAdded:
+			cur = n
+			_, ok = ma[n.Prefix()]
+			if o != nil || ok {
+				t.Fatalf("Bad added event for %s", n.Prefix())
+			}
+			delete(mb, n.Prefix())
+		case DiffChanged:
+			cur = n
+			if o.Prefix() != n.Prefix() || o.Data == n.Data {
+				t.Fatalf("Bad changed event for %s", n.Prefix())
+			}
+			delete(ma, o.Prefix())
+			delete(mb, n.Prefix())
+		}
+
+		/* Events are reported in tree order */
+		if last != nil && prefix_compare([]byte(last.node.Bytes), last.node.End + 1, []byte(cur.node.Bytes), cur.node.End + 1) >= 0 {
+			t.Fatalf("Event for %s not in order", cur.Prefix())
+		}
+		last = cur
+		return true
+	})
+
+	/* Remaining prefixes are common with equal data */
+	for p, v = range ma {
+		_, ok = mb[p]
+		if !ok || mb[p] != v {
+			t.Fatalf("Missing event for %s", p)
+		}
+		delete(mb, p)
+	}
+	if len(mb) != 0 {
+		t.Fatalf("Missing added events")
+	}
+
+	/* Stop */
+	Diff(a, NewTree[int](), nil, func(kind DiffKind, o *Leaf[int], n *Leaf[int])(bool) {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("Expect diff stops after 10 events, got %d", count)
+	}
+}
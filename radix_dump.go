@@ -0,0 +1,215 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bufio"
+import "encoding/binary"
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "io"
+import "net"
+import "strconv"
+import "time"
+
+// KeyFormatter convert a key/length prefix to a string for the dumps.
+// length is the number of significant bits of the key.
+type KeyFormatter func(key []byte, length int16)(string)
+
+/* Guess the key kind: IPv4 or IPv6 network, otherwise hexadecimal */
+func format_auto(key []byte, length int16)(string) {
+	var b []byte
+
+	if len(key) <= 4 && length <= 33 {
+		b = make([]byte, len(key))
+		copy(b, key)
+		for len(b) < 4 {
+			b = append([]byte{0x00}, b...)
+		}
+		return FormatIPv4(b, length)
+	}
+	if len(key) == 16 && length <= 128 {
+		return FormatIPv6(key, length)
+	}
+	return hex.EncodeToString(key)
+}
+
+/* Format network, the key must contains size bytes */
+func format_ip(key []byte, length int16, size int)(string) {
+	var ip net.IPNet
+
+	if len(key) < size || length < 0 || int(length) > size * 8 {
+		return FormatHex(key, length)
+	}
+	ip.Mask = net.CIDRMask(int(length), size * 8)
+	ip.IP = net.IP(key[:size]).Mask(ip.Mask)
+	return ip.String()
+}
+
+// FormatIPv4 format the key as IPv4 network.
+func FormatIPv4(key []byte, length int16)(string) {
+	return format_ip(key, length, 4)
+}
+
+// FormatIPv6 format the key as IPv6 network.
+func FormatIPv6(key []byte, length int16)(string) {
+	return format_ip(key, length, 16)
+}
+
+// FormatString format the key as a quoted string. If the length does
+// not ends on a byte, the length in bits is appended.
+func FormatString(key []byte, length int16)(string) {
+	var size int
+
+	size = (int(length) + 7) / 8
+	if size > len(key) || length < 0 {
+		return FormatHex(key, length)
+	}
+	if length % 8 != 0 {
+		return strconv.Quote(string(key[:size])) + "/" + strconv.Itoa(int(length))
+	}
+	return strconv.Quote(string(key[:size]))
+}
+
+/* Return the 64 bits value of the key masked with length */
+func format_uint64(key []byte, length int16)(uint64, bool) {
+	var v uint64
+
+	if len(key) < 8 || length < 0 || length > 64 {
+		return 0, false
+	}
+	v = binary.BigEndian.Uint64(key)
+	if length < 64 {
+		v &^= (1 << uint(64 - length)) - 1
+	}
+	return v, true
+}
+
+// FormatUInt64 format the key as unsigned integer. If the length is
+// lower than 64, the length in bits is appended.
+func FormatUInt64(key []byte, length int16)(string) {
+	var v uint64
+	var ok bool
+
+	v, ok = format_uint64(key, length)
+	if !ok {
+		return FormatHex(key, length)
+	}
+	if length < 64 {
+		return strconv.FormatUint(v, 10) + "/" + strconv.Itoa(int(length))
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// FormatTime format the key as UTC date. If the length is lower than
+// 64, the length in bits is appended.
+func FormatTime(key []byte, length int16)(string) {
+	var v uint64
+	var ok bool
+	var s string
+
+	v, ok = format_uint64(key, length)
+	if !ok {
+		return FormatHex(key, length)
+	}
+	s = time.UnixMicro(int64(v)).UTC().Format(time.RFC3339Nano)
+	if length < 64 {
+		return s + "/" + strconv.Itoa(int(length))
+	}
+	return s
+}
+
+// FormatHex format the key as hexadecimal followed by the length in bits.
+func FormatHex(key []byte, length int16)(string) {
+	return hex.EncodeToString(key) + "/" + strconv.Itoa(int(length))
+}
+
+func (r *Tree[V])dump_dot(w *bufio.Writer, f KeyFormatter, ref uint32) {
+	var n *node
+	var shape string
+	var side int
+	var child uint32
+
+	n = r.r2n(ref)
+	if is_leaf(ref) {
+		shape = "box"
+	} else {
+		shape = "ellipse"
+	}
+	fmt.Fprintf(w, "\tn%08x [shape=%s, label=%s];\n", ref, shape,
+	            strconv.Quote(fmt.Sprintf("%s\nstart=%d end=%d", f([]byte(n.Bytes), n.End + 1), n.Start, n.End)))
+	for side, child = range []uint32{n.Left, n.Right} {
+		if child == null {
+			continue
+		}
+		fmt.Fprintf(w, "\tn%08x -> n%08x [label=\"%d\"];\n", ref, child, side)
+		r.dump_dot(w, f, child)
+	}
+}
+
+// DumpDOT write the tree structure in w as a Graphviz graph. The leaves
+// are boxes and the internal nodes are ellipses, each one is labeled with
+// its prefix formatted by f and its start and end bits. If f is nil, the
+// key kind is guessed.
+func (r *Tree[V])DumpDOT(w io.Writer, f KeyFormatter)(error) {
+	var bw *bufio.Writer
+
+	if f == nil {
+		f = format_auto
+	}
+	bw = bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph radix {\n")
+	if r.Node != null {
+		r.dump_dot(bw, f, r.Node)
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+type dump_json_node struct {
+	Ref uint32 `json:"ref"`
+	Leaf bool `json:"leaf"`
+	Key string `json:"key"`
+	Start int16 `json:"start"`
+	End int16 `json:"end"`
+	Left *dump_json_node `json:"left,omitempty"`
+	Right *dump_json_node `json:"right,omitempty"`
+}
+
+type dump_json_tree struct {
+	Length int `json:"length"`
+	Root *dump_json_node `json:"root"`
+}
+
+func (r *Tree[V])dump_json(f KeyFormatter, ref uint32)(*dump_json_node) {
+	var n *node
+	var d *dump_json_node
+
+	if ref == null {
+		return nil
+	}
+	n = r.r2n(ref)
+	d = &dump_json_node{
+		Ref: ref,
+		Leaf: is_leaf(ref),
+		Key: f([]byte(n.Bytes), n.End + 1),
+		Start: n.Start,
+		End: n.End,
+	}
+	d.Left = r.dump_json(f, n.Left)
+	d.Right = r.dump_json(f, n.Right)
+	return d
+}
+
+// DumpJSON write the tree structure in w as JSON. Each node contains its
+// reference, its kind, its prefix formatted by f, its start and end bits
+// and its children. If f is nil, the key kind is guessed.
+func (r *Tree[V])DumpJSON(w io.Writer, f KeyFormatter)(error) {
+	if f == nil {
+		f = format_auto
+	}
+	return json.NewEncoder(w).Encode(&dump_json_tree{
+		Length: r.length,
+		Root: r.dump_json(f, r.Node),
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "encoding/json"
+import "net"
+import "strings"
+import "testing"
+import "time"
+
+func TestKeyFormatter(t *testing.T) {
+	var key []byte
+	var s string
+
+	key = []byte{10, 1, 0, 0}
+	s = FormatIPv4(key, 16)
+	if s != "10.1.0.0/16" {
+		t.Errorf("FormatIPv4: got %q", s)
+	}
+	key = net.ParseIP("2001:db8::")
+	s = FormatIPv6(key, 32)
+	if s != "2001:db8::/32" {
+		t.Errorf("FormatIPv6: got %q", s)
+	}
+	key = []byte("foo")
+	s = FormatString(key, 24)
+	if s != "\"foo\"" {
+		t.Errorf("FormatString: got %q", s)
+	}
+	s = FormatString(key, 20)
+	if s != "\"foo\"/20" {
+		t.Errorf("FormatString: got %q", s)
+	}
+	key = uint64_to_key(1234)
+	s = FormatUInt64(key, 64)
+	if s != "1234" {
+		t.Errorf("FormatUInt64: got %q", s)
+	}
+	s = FormatUInt64(key, 60)
+	if s != "1232/60" {
+		t.Errorf("FormatUInt64: got %q", s)
+	}
+	key = time_to_key(time.Date(2024, 3, 1, 12, 0, 0, 5000, time.UTC))
+	s = FormatTime(key, 64)
+	if s != "2024-03-01T12:00:00.000005Z" {
+		t.Errorf("FormatTime: got %q", s)
+	}
+	key = []byte{0xab, 0xcd}
+	s = FormatHex(key, 12)
+	if s != "abcd/12" {
+		t.Errorf("FormatHex: got %q", s)
+	}
+
+	/* Invalid length falls back to hexadecimal */
+	key = []byte{10, 1}
+	s = FormatIPv4(key, 16)
+	if s != "0a01/16" {
+		t.Errorf("FormatIPv4: got %q", s)
+	}
+}
+
+func TestDumpDOT(t *testing.T) {
+	var r *Tree[int]
+	var buf bytes.Buffer
+	var out string
+	var err error
+
+	r = NewTree[int]()
+	err = r.DumpDOT(&buf, nil)
+	if err != nil {
+		t.Fatalf("DumpDOT: %v", err)
+	}
+	if buf.String() != "digraph radix {\n}\n" {
+		t.Errorf("Empty tree: got %q", buf.String())
+	}
+
+	r.StringInsert("foo", 1)
+	r.StringInsert("far", 2)
+	r.StringInsert("foobar", 3)
+	buf.Reset()
+	err = r.DumpDOT(&buf, FormatString)
+	if err != nil {
+		t.Fatalf("DumpDOT: %v", err)
+	}
+	out = buf.String()
+	if !strings.HasPrefix(out, "digraph radix {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("Bad graph enclosure: %q", out)
+	}
+	if strings.Count(out, "shape=box") != 3 {
+		t.Errorf("Expect 3 leaves: %q", out)
+	}
+	if strings.Count(out, "shape=ellipse") != 1 {
+		t.Errorf("Expect 1 internal node: %q", out)
+	}
+	if strings.Count(out, " -> ") != 3 {
+		t.Errorf("Expect 3 edges: %q", out)
+	}
+	if !strings.Contains(out, "\\\"foobar\\\"\\nstart=24 end=47") {
+		t.Errorf("Missing foobar label: %q", out)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	var r *Tree[int]
+	var buf bytes.Buffer
+	var d dump_json_tree
+	var leaves int
+	var nodes int
+	var walk func(n *dump_json_node, start int16)
+	var err error
+
+	r = NewTree[int]()
+	r.IPv4Insert(&net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}, 1)
+	r.IPv4Insert(&net.IPNet{IP: net.IPv4(10, 1, 0, 0), Mask: net.CIDRMask(16, 32)}, 2)
+	r.IPv4Insert(&net.IPNet{IP: net.IPv4(10, 2, 0, 0), Mask: net.CIDRMask(16, 32)}, 3)
+	r.IPv4Insert(&net.IPNet{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)}, 4)
+
+	err = r.DumpJSON(&buf, FormatIPv4)
+	if err != nil {
+		t.Fatalf("DumpJSON: %v", err)
+	}
+	err = json.Unmarshal(buf.Bytes(), &d)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if d.Length != 4 {
+		t.Errorf("Expect length 4, got %d", d.Length)
+	}
+
+	walk = func(n *dump_json_node, start int16) {
+		if n.Start != start {
+			t.Errorf("Node %s start %d, expect %d", n.Key, n.Start, start)
+		}
+		if n.Leaf {
+			leaves++
+		} else {
+			nodes++
+			if n.Left == nil || n.Right == nil {
+				t.Errorf("Internal node %s without two children", n.Key)
+			}
+		}
+		if n.Left != nil {
+			walk(n.Left, n.End + 1)
+		}
+		if n.Right != nil {
+			walk(n.Right, n.End + 1)
+		}
+	}
+	walk(d.Root, 0)
+	if leaves != 4 {
+		t.Errorf("Expect 4 leaves, got %d", leaves)
+	}
+	if d.Root.Key != "0.0.0.0/0" || d.Root.Left.Key != "10.0.0.0/8" {
+		t.Errorf("Unexpected keys %q %q", d.Root.Key, d.Root.Left.Key)
+	}
+}
@@ -2,21 +2,31 @@
 
 package radix
 
+import "iter"
 import "net"
 
 func network_to_key(network *net.IPNet)([]byte, int16) {
 	var l int
-	var length int16
-
-	/* Get the network width. width of 0 id prohibited */
-	l, _ = network.Mask.Size()
-	length = int16(l)
-	return []byte(network.IP.To4()), length
+	var bits int
+	var ip net.IP
+
+	/* Reject IPv6 networks, To4() returns nil for them and
+	 * the key will be empty.
+	 */
+	l, bits = network.Mask.Size()
+	if bits != 32 {
+		return nil, 0
+	}
+	ip = network.IP.To4()
+	if ip == nil {
+		return nil, 0
+	}
+	return []byte(ip), int16(l)
 }
 
 // IPv4LookupLonguest get a ipv4 network and return the leaf which match the
 // longest part of the prefix. Return nil if none match.
-func (r *Radix)IPv4LookupLonguest(network *net.IPNet)(*Node) {
+func (r *Tree[V])IPv4LookupLonguest(network *net.IPNet)(*Leaf[V]) {
 	var length int16
 	var key []byte
 
@@ -30,16 +40,32 @@ func (r *Radix)IPv4LookupLonguest(network *net.IPNet)(*Node) {
 	return r.LookupLonguest(&key, length)
 }
 
+// IPv4LookupShortest get a ipv4 network and return the leaf which match the
+// shortest part of the prefix. Return nil if none match.
+func (r *Tree[V])IPv4LookupShortest(network *net.IPNet)(*Leaf[V]) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network_to_key(network)
+	if length == 0 {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.LookupShortest(&key, length)
+}
+
 // IPv4LookupLonguestPath take the radix tree and a ipv4 network, return the list
 // of all leaf matching the prefix. If none match, return nil
-func (r *Radix)IPv4LookupLonguestPath(network *net.IPNet)([]*Node) {
+func (r *Tree[V])IPv4LookupLonguestPath(network *net.IPNet)([]*Leaf[V]) {
 	var length int16
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
 	key, length = network_to_key(network)
 	if length == 0 {
-		return make([]*Node, 0)
+		return make([]*Leaf[V], 0)
 	}
 
 	/* Perform lookup */
@@ -48,7 +74,7 @@ func (r *Radix)IPv4LookupLonguestPath(network *net.IPNet)([]*Node) {
 
 // IPv4Get gets a ipv4 network and return exact match of the prefix. Exact match
 // is a node wich match the prefix bit and the length.
-func (r *Radix)IPv4Get(network *net.IPNet)(*Node) {
+func (r *Tree[V])IPv4Get(network *net.IPNet)(*Leaf[V]) {
 	var length int16
 	var key []byte
 
@@ -65,7 +91,7 @@ func (r *Radix)IPv4Get(network *net.IPNet)(*Node) {
 // IPv4Insert ipv4 network in the tree. The tree accept only unique value, if
 // the prefix already exists in the tree, return existing leaf,
 // otherwaise return nil.
-func (r *Radix)IPv4Insert(network *net.IPNet, data interface{})(*Node, bool) {
+func (r *Tree[V])IPv4Insert(network *net.IPNet, data V)(*Leaf[V], bool) {
 	var length int16
 	var key []byte
 
@@ -81,8 +107,8 @@ func (r *Radix)IPv4Insert(network *net.IPNet, data interface{})(*Node, bool) {
 
 // IPv4DeleteNetwork lookup network and remove it. does nothing
 // if the network not exists.
-func (r *Radix)IPv4DeleteNetwork(network *net.IPNet)() {
-	var node *Node
+func (r *Tree[V])IPv4DeleteNetwork(network *net.IPNet)() {
+	var node *Leaf[V]
 	var length int16
 	var key []byte
 
@@ -103,7 +129,7 @@ func (r *Radix)IPv4DeleteNetwork(network *net.IPNet)() {
 }
 
 // IPv4GetNet convert node key/length prefix to IPv4 network data
-func (n *Node)IPv4GetNet()(* net.IPNet) {
+func (n *Leaf[V])IPv4GetNet()(* net.IPNet) {
 	var network *net.IPNet
 
 	network = &net.IPNet{}
@@ -115,10 +141,109 @@ func (n *Node)IPv4GetNet()(* net.IPNet) {
 
 // IPv4NewIter return struct Iter for browsing all nodes there children
 // match the ipv4 network
-func (r *Radix)IPv4NewIter(network *net.IPNet)(*Iter) {
+func (r *Tree[V])IPv4NewIter(network *net.IPNet)(*Iterator[V]) {
 	var length int16
 	var key []byte
 
 	key, length = network_to_key(network)
+	if key == nil {
+		return &Iterator[V]{}
+	}
 	return r.NewIter(&key, length)
 }
+
+// IPv4Prefixed return an iterator on the network and the data of all
+// the leaves matching the ipv4 network, in tree order.
+func (r *Tree[V])IPv4Prefixed(network *net.IPNet)(iter.Seq2[*net.IPNet, V]) {
+	return func(yield func(*net.IPNet, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = network_to_key(network)
+		if key == nil {
+			return
+		}
+		for n = range r.Prefixed(&key, length) {
+			if !yield(n.IPv4GetNet(), n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// IPv4NewReverseIter return struct Iter for browsing all nodes there
+// children match the ipv4 network, from the last one to the first one.
+func (r *Tree[V])IPv4NewReverseIter(network *net.IPNet)(*Iterator[V]) {
+	var length int16
+	var key []byte
+
+	key, length = network_to_key(network)
+	if key == nil {
+		return &Iterator[V]{}
+	}
+	return r.NewReverseIter(&key, length)
+}
+
+// IPv4Covering return an iterator on the network and the data of the
+// leaves covering the ipv4 network, from the least specific to the most
+// specific.
+func (r *Tree[V])IPv4Covering(network *net.IPNet)(iter.Seq2[*net.IPNet, V]) {
+	return func(yield func(*net.IPNet, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = network_to_key(network)
+		if length == 0 {
+			return
+		}
+		for n = range r.Covering(&key, length) {
+			if !yield(n.IPv4GetNet(), n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// IPv4CoveringBackward return an iterator on the network and the data of
+// the leaves covering the ipv4 network, from the most specific to the
+// least specific.
+func (r *Tree[V])IPv4CoveringBackward(network *net.IPNet)(iter.Seq2[*net.IPNet, V]) {
+	return func(yield func(*net.IPNet, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = network_to_key(network)
+		if length == 0 {
+			return
+		}
+		for n = range r.CoveringBackward(&key, length) {
+			if !yield(n.IPv4GetNet(), n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// IPv4LookupOverlapping return an iterator on the leaves overlapping the
+// ipv4 network with their relation to the network, in tree order.
+func (r *Tree[V])IPv4LookupOverlapping(network *net.IPNet)(iter.Seq2[*Leaf[V], Overlap]) {
+	return func(yield func(*Leaf[V], Overlap)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+		var o Overlap
+
+		key, length = network_to_key(network)
+		if length == 0 {
+			return
+		}
+		for n, o = range r.LookupOverlapping(&key, length) {
+			if !yield(n, o) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "encoding/binary"
+import "net"
+
+// IPv4Range is an inclusive range of IPv4 addresses.
+type IPv4Range struct {
+	Start net.IP
+	End net.IP
+}
+
+func ipv4_to_uint(ip net.IP)(uint64, bool) {
+	ip = ip.To4()
+	if ip == nil {
+		return 0, false
+	}
+	return uint64(binary.BigEndian.Uint32(ip)), true
+}
+
+func uint_to_ipv4(v uint64)(net.IP) {
+	var ip net.IP
+
+	ip = make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, uint32(v))
+	return ip
+}
+
+// IPv4RangeToPrefixes return the minimal list of aligned prefixes covering
+// exactly the range of addresses from start to end, both included. The
+// prefixes are sorted. Because the tree refuse 0 length prefix, the full
+// address space gives two /1 prefixes. Return nil if start or end are not
+// IPv4 addresses or if start is greater than end.
+func IPv4RangeToPrefixes(start net.IP, end net.IP)([]*net.IPNet) {
+	var prefixes []*net.IPNet
+	var s uint64
+	var e uint64
+	var size uint64
+	var bits int
+	var ok bool
+
+	s, ok = ipv4_to_uint(start)
+	if !ok {
+		return nil
+	}
+	e, ok = ipv4_to_uint(end)
+	if !ok || s > e {
+		return nil
+	}
+
+	for s <= e {
+
+		/* Get the largest block aligned on s and included in the range */
+		bits = 32
+		size = 1
+		for bits > 1 && s & (size << 1 - 1) == 0 && s + (size << 1) - 1 <= e {
+			bits--
+			size <<= 1
+		}
+		prefixes = append(prefixes, &net.IPNet{
+			IP: uint_to_ipv4(s),
+			Mask: net.CIDRMask(bits, 32),
+		})
+		s += size
+	}
+	return prefixes
+}
+
+// IPv4InsertRange insert the minimal list of prefixes covering the range of
+// addresses from start to end, both included, with the same data. It
+// return the inserted leaves, if a prefix already exists, the existing leaf
+// is returned and its data is not modified. Return nil if the range is not
+// valid.
+func (r *Tree[V])IPv4InsertRange(start net.IP, end net.IP, data V)([]*Leaf[V]) {
+	var prefixes []*net.IPNet
+	var leaves []*Leaf[V]
+	var i int
+
+	prefixes = IPv4RangeToPrefixes(start, end)
+	if prefixes == nil {
+		return nil
+	}
+	leaves = make([]*Leaf[V], len(prefixes))
+	for i = range prefixes {
+		leaves[i], _ = r.IPv4Insert(prefixes[i], data)
+	}
+	return leaves
+}
+
+// IPv4Ranges return the sorted list of ranges covered by the IPv4 prefixes
+// of the tree. Overlapping and adjacent prefixes are merged in one range,
+// the data are not considered.
+func (r *Tree[V])IPv4Ranges()([]IPv4Range) {
+	var ranges []IPv4Range
+	var it *Iterator[V]
+	var n *Leaf[V]
+	var s uint64
+	var e uint64
+	var cur_s uint64
+	var cur_e uint64
+	var found bool
+
+	it = r.NewIter(nil, 0)
+	for it.Next() {
+		n = it.Get()
+		if len(n.node.Bytes) != 4 {
+			continue
+		}
+
+		/* The tree order gives the prefixes sorted by start address */
+		s = uint64(binary.BigEndian.Uint32([]byte(n.node.Bytes)))
+		s &^= 1 << (31 - uint(n.node.End)) - 1
+		e = s + 1 << (31 - uint(n.node.End)) - 1
+		if found && s <= cur_e + 1 {
+			if e > cur_e {
+				cur_e = e
+			}
+			continue
+		}
+		if found {
+			ranges = append(ranges, IPv4Range{uint_to_ipv4(cur_s), uint_to_ipv4(cur_e)})
+		}
+		cur_s = s
+		cur_e = e
+		found = true
+	}
+	if found {
+		ranges = append(ranges, IPv4Range{uint_to_ipv4(cur_s), uint_to_ipv4(cur_e)})
+	}
+	return ranges
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "math/rand"
+import "net"
+import "testing"
+
+func TestIPv4RangeToPrefixes(t *testing.T) {
+	var prefixes []*net.IPNet
+	var expect []string
+	var i int
+
+	prefixes = IPv4RangeToPrefixes(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.1.128"))
+	expect = []string{
+		"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/29",
+		"10.0.0.16/28", "10.0.0.32/27", "10.0.0.64/26", "10.0.0.128/25",
+		"10.0.1.0/25", "10.0.1.128/32",
+	}
+	if len(prefixes) != len(expect) {
+		t.Fatalf("Expect %v, got %v", expect, prefixes)
+	}
+	for i = range prefixes {
+		if prefixes[i].String() != expect[i] {
+			t.Fatalf("Expect %v, got %v", expect, prefixes)
+		}
+	}
+
+	prefixes = IPv4RangeToPrefixes(net.ParseIP("0.0.0.0"), net.ParseIP("255.255.255.255"))
+	if len(prefixes) != 2 || prefixes[0].String() != "0.0.0.0/1" || prefixes[1].String() != "128.0.0.0/1" {
+		t.Errorf("Expect two /1, got %v", prefixes)
+	}
+
+	prefixes = IPv4RangeToPrefixes(net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1"))
+	if prefixes != nil {
+		t.Errorf("Expect nil for reversed range, got %v", prefixes)
+	}
+	prefixes = IPv4RangeToPrefixes(net.ParseIP("::1"), net.ParseIP("::2"))
+	if prefixes != nil {
+		t.Errorf("Expect nil for IPv6 range, got %v", prefixes)
+	}
+}
+
+func TestIPv4InsertRange(t *testing.T) {
+	var r *Radix
+	var leaves []*Leaf[any]
+	var ranges []IPv4Range
+	var s uint32
+	var e uint32
+	var i int
+
+	r = NewRadix()
+	leaves = r.IPv4InsertRange(net.ParseIP("192.168.0.10"), net.ParseIP("192.168.0.20"), "a")
+	if len(leaves) != 4 || r.Len() != 4 {
+		t.Errorf("Expect 4 leaves, got %d", len(leaves))
+	}
+	r.IPv4InsertRange(net.ParseIP("192.168.0.21"), net.ParseIP("192.168.0.30"), "b")
+	r.IPv4InsertRange(net.ParseIP("192.168.0.16"), net.ParseIP("192.168.0.17"), "c")
+	r.IPv4InsertRange(net.ParseIP("10.0.0.0"), net.ParseIP("10.0.0.0"), "d")
+
+	ranges = r.IPv4Ranges()
+	if len(ranges) != 2 ||
+	   !ranges[0].Start.Equal(net.ParseIP("10.0.0.0")) || !ranges[0].End.Equal(net.ParseIP("10.0.0.0")) ||
+	   !ranges[1].Start.Equal(net.ParseIP("192.168.0.10")) || !ranges[1].End.Equal(net.ParseIP("192.168.0.30")) {
+		t.Errorf("Unexpected ranges %v", ranges)
+	}
+
+	/* Random ranges must be restored */
+	for i = 0; i < 1000; i++ {
+		s = rand.Uint32()
+		e = s + uint32(rand.Intn(1 << 20))
+		if e < s {
+			continue
+		}
+		r = NewRadix()
+		r.IPv4InsertRange(uint_to_ipv4(uint64(s)), uint_to_ipv4(uint64(e)), nil)
+		ranges = r.IPv4Ranges()
+		if len(ranges) != 1 || !ranges[0].Start.Equal(uint_to_ipv4(uint64(s))) || !ranges[0].End.Equal(uint_to_ipv4(uint64(e))) {
+			t.Fatalf("Range %d-%d not restored, got %v", s, e, ranges)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "iter"
+import "net"
+
+/* IPv6 and IPv4 keys share the same bit space, so a tree should
+ * contain only one address family.
+ */
+func network6_to_key(network *net.IPNet)([]byte, int16) {
+	var l int
+	var bits int
+	var ip net.IP
+
+	/* Reject IPv4 networks, their mask is 32 bits */
+	l, bits = network.Mask.Size()
+	if bits != 128 {
+		return nil, 0
+	}
+	ip = network.IP.To16()
+	if ip == nil {
+		return nil, 0
+	}
+	return []byte(ip), int16(l)
+}
+
+// IPv6LookupLonguest get a ipv6 network and return the leaf which match the
+// longest part of the prefix. Return nil if none match.
+func (r *Tree[V])IPv6LookupLonguest(network *net.IPNet)(*Leaf[V]) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network6_to_key(network)
+	if length == 0 {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.LookupLonguest(&key, length)
+}
+
+// IPv6LookupLonguestPath take the radix tree and a ipv6 network, return the list
+// of all leaf matching the prefix. If none match, return nil
+func (r *Tree[V])IPv6LookupLonguestPath(network *net.IPNet)([]*Leaf[V]) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network6_to_key(network)
+	if length == 0 {
+		return make([]*Leaf[V], 0)
+	}
+
+	/* Perform lookup */
+	return r.LookupLonguestPath(&key, length)
+}
+
+// IPv6Get gets a ipv6 network and return exact match of the prefix. Exact match
+// is a node wich match the prefix bit and the length.
+func (r *Tree[V])IPv6Get(network *net.IPNet)(*Leaf[V]) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network6_to_key(network)
+	if length == 0 {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.Get(&key, length)
+}
+
+// IPv6Insert ipv6 network in the tree. The tree accept only unique value, if
+// the prefix already exists in the tree, return existing leaf,
+// otherwaise return nil.
+func (r *Tree[V])IPv6Insert(network *net.IPNet, data V)(*Leaf[V], bool) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network6_to_key(network)
+	if length == 0 {
+		return nil, false
+	}
+
+	/* Perform insert */
+	return r.Insert(&key, length, data)
+}
+
+// IPv6DeleteNetwork lookup network and remove it. does nothing
+// if the network not exists.
+func (r *Tree[V])IPv6DeleteNetwork(network *net.IPNet)() {
+	var node *Leaf[V]
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = network6_to_key(network)
+	if length == 0 {
+		return
+	}
+
+	/* Perform lookup */
+	node = r.Get(&key, length)
+	if node == nil {
+		return
+	}
+
+	/* Delete entry */
+	r.Delete(node)
+}
+
+// IPv6GetNet convert node key/length prefix to IPv6 network data
+func (n *Leaf[V])IPv6GetNet()(* net.IPNet) {
+	var network *net.IPNet
+
+	network = &net.IPNet{}
+	network.Mask = net.CIDRMask(int(n.node.End) + 1, 128)
+	network.IP = net.IP(n.node.Bytes).Mask(network.Mask)
+
+	return network
+}
+
+// IPv6NewIter return struct Iter for browsing all nodes there children
+// match the ipv6 network
+func (r *Tree[V])IPv6NewIter(network *net.IPNet)(*Iterator[V]) {
+	var length int16
+	var key []byte
+
+	key, length = network6_to_key(network)
+	if key == nil {
+		return &Iterator[V]{}
+	}
+	return r.NewIter(&key, length)
+}
+
+// IPv6Prefixed return an iterator on the network and the data of all
+// the leaves matching the ipv6 network, in tree order.
+func (r *Tree[V])IPv6Prefixed(network *net.IPNet)(iter.Seq2[*net.IPNet, V]) {
+	return func(yield func(*net.IPNet, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = network6_to_key(network)
+		if key == nil {
+			return
+		}
+		for n = range r.Prefixed(&key, length) {
+			if !yield(n.IPv6GetNet(), n.Data) {
+				return
+			}
+		}
+	}
+}
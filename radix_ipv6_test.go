@@ -0,0 +1,155 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "net"
+import "strings"
+import "testing"
+
+func TestRadixIPv6Order(t *testing.T) {
+	var r *Radix
+	var reference []string
+	var pfx []string
+	var s string
+	var n *net.IPNet
+	var a *Node
+	var index int
+
+	/* This is a sorted reference of networks */
+	reference = []string{
+		"2001:db8::/32",
+		"2001:db8::/48",
+		"2001:db8::/64",
+		"2001:db8::1/128",
+		"2001:db8:0:1::/64",
+		"2001:db8:8000::/33",
+		"2001:db9::/32",
+	}
+
+	pfx = []string{
+		reference[4],
+		reference[1],
+		reference[6],
+		reference[0],
+		reference[3],
+		reference[5],
+		reference[2],
+	}
+
+	r = NewRadix()
+	for _, s = range pfx {
+		_, n, _ = net.ParseCIDR(s)
+		r.IPv6Insert(n, s)
+	}
+
+	if r.Len() != len(reference) {
+		t.Errorf("expect %d entries, got %d", len(reference), r.Len())
+	}
+
+	index = 0
+	for a = r.First(); a != nil; a = r.Next(a) {
+		if a.IPv6GetNet().String() != reference[index] {
+			t.Errorf("something is wrong in sort order at index %d, expect %q, got %q",
+			         index, reference[index], a.IPv6GetNet().String())
+		}
+		if a.Data.(string) != reference[index] {
+			t.Errorf("expect data %q, got %q", reference[index], a.Data.(string))
+		}
+		index++
+	}
+}
+
+func TestRadixIPv6(t *testing.T) {
+	var r *Radix
+	var nw *net.IPNet
+	var n *Node
+	var ns []*Node
+	var it *Iter
+	var count int
+	var ok bool
+	var out bytes.Buffer
+
+	r = NewRadix()
+
+	_, nw, _ = net.ParseCIDR("2001:db8::/32")
+	r.IPv6Insert(nw, "2001:db8::/32")
+	_, nw, _ = net.ParseCIDR("2001:db8:1::/48")
+	r.IPv6Insert(nw, "2001:db8:1::/48")
+	_, nw, _ = net.ParseCIDR("2001:db8:2::/48")
+	r.IPv6Insert(nw, "2001:db8:2::/48")
+
+	/* IPv4 network are rejected */
+	_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+	_, ok = r.IPv6Insert(nw, "10.0.0.0/8")
+	if ok || r.Len() != 3 {
+		t.Errorf("IPv4 network should not be inserted")
+	}
+	if r.IPv4Insert(nw, "10.0.0.0/8"); r.Len() != 4 {
+		t.Errorf("IPv4 network should be inserted")
+	}
+	r.IPv4DeleteNetwork(nw)
+
+	/* IPv6 network are rejected by IPv4 functions */
+	_, nw, _ = net.ParseCIDR("2001:db8::/32")
+	if n = r.IPv4Get(nw); n != nil {
+		t.Errorf("IPv6 network should not match IPv4 functions")
+	}
+
+	/* Exact match */
+	_, nw, _ = net.ParseCIDR("2001:db8:1::/48")
+	n = r.IPv6Get(nw)
+	if n == nil || n.Data.(string) != "2001:db8:1::/48" {
+		t.Errorf("2001:db8:1::/48 should match")
+	}
+
+	/* Longest match */
+	_, nw, _ = net.ParseCIDR("2001:db8:1::1/128")
+	n = r.IPv6LookupLonguest(nw)
+	if n == nil || n.IPv6GetNet().String() != "2001:db8:1::/48" {
+		t.Errorf("2001:db8:1::1/128 should match 2001:db8:1::/48")
+	}
+	_, nw, _ = net.ParseCIDR("2001:db8:3::1/128")
+	n = r.IPv6LookupLonguest(nw)
+	if n == nil || n.IPv6GetNet().String() != "2001:db8::/32" {
+		t.Errorf("2001:db8:3::1/128 should match 2001:db8::/32")
+	}
+
+	/* Longest match path */
+	_, nw, _ = net.ParseCIDR("2001:db8:2::1/128")
+	ns = r.IPv6LookupLonguestPath(nw)
+	if len(ns) != 2 {
+		t.Errorf("Should have length of 2, got %d", len(ns))
+	}
+
+	/* Iter */
+	_, nw, _ = net.ParseCIDR("2001:db8::/32")
+	it = r.IPv6NewIter(nw)
+	for it.Next() {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Should iterate over 3 entries, got %d", count)
+	}
+	_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+	it = r.IPv6NewIter(nw)
+	if it.Next() {
+		t.Errorf("IPv4 network should not iterate")
+	}
+
+	/* Debug display IPv6 prefixes */
+	r.Debug(&out)
+	if !strings.Contains(out.String(), "key=2001:db8:1::/48") {
+		t.Errorf("Debug should display IPv6 prefixes, got:\n%s", out.String())
+	}
+
+	/* Delete */
+	_, nw, _ = net.ParseCIDR("2001:db8:1::/48")
+	r.IPv6DeleteNetwork(nw)
+	if r.IPv6Get(nw) != nil {
+		t.Errorf("2001:db8:1::/48 should be deleted")
+	}
+	if r.Len() != 2 {
+		t.Errorf("Expect 2 entries, got %d", r.Len())
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "iter"
+
+/* Return the last node of the subtree in tree order */
+func (r *Tree[V])last_node(ref uint32)(uint32) {
+	var n *node
+
+	for {
+		n = r.r2n(ref)
+		if n.Right != null {
+			ref = n.Right
+		} else if n.Left != null {
+			ref = n.Left
+		} else {
+			return ref
+		}
+	}
+}
+
+/* Return the node before ref in tree order. Return null if ref is
+ * the first node.
+ */
+func (r *Tree[V])prev_node(ref uint32)(uint32) {
+	var n *node
+	var p *node
+
+	n = r.r2n(ref)
+	if n.Parent == null {
+		return null
+	}
+	p = r.r2n(n.Parent)
+	if p.Right == ref && p.Left != null {
+		return r.last_node(p.Left)
+	}
+	return n.Parent
+}
+
+/* Return the leaf before ref in tree order */
+func (r *Tree[V])prev_leaf(ref uint32)(uint32) {
+	for {
+		ref = r.prev_node(ref)
+		if ref == null || is_leaf(ref) {
+			return ref
+		}
+	}
+}
+
+/* Browse the tree from ref along the key/length prefix and return the
+ * first leaf covering the prefix. Return null if none.
+ */
+func (r *Tree[V])covering_from(ref uint32, data []byte, length int16)(uint32) {
+	var n *node
+	var end int16
+
+	length-- /* convert length to index of last bit */
+	for ref != null {
+		n = r.r2n(ref)
+		end = n.End
+		if length < end || (end != -1 && !bitcmp([]byte(n.Bytes), data, n.Start, end)) {
+			return null
+		}
+		if is_leaf(ref) {
+			return ref
+		}
+		if length <= end {
+			return null
+		}
+		end++
+		if data[end / 8] & (0x80 >> (end % 8)) != 0 {
+			ref = n.Right
+		} else {
+			ref = n.Left
+		}
+	}
+	return null
+}
+
+/* Return the next leaf covering the key/length prefix after the
+ * covering leaf ref, from the least specific to the most specific.
+ */
+func (r *Tree[V])covering_next(ref uint32, data []byte, length int16)(uint32) {
+	var n *node
+	var end int16
+
+	n = r.r2n(ref)
+	end = n.End + 1
+	if end >= length {
+		return null
+	}
+	if data[end / 8] & (0x80 >> (end % 8)) != 0 {
+		return r.covering_from(n.Right, data, length)
+	}
+	return r.covering_from(n.Left, data, length)
+}
+
+/* Return the leaf parent of ref. Return null if none */
+func (r *Tree[V])parent_leaf(ref uint32)(uint32) {
+	for {
+		ref = r.r2n(ref).Parent
+		if ref == null || is_leaf(ref) {
+			return ref
+		}
+	}
+}
+
+// All return an iterator on all the leaves of the tree in tree order.
+// The next leaf is looked up before the current leaf is returned, so
+// the current leaf could be deleted during the iteration.
+func (r *Tree[V])All()(iter.Seq[*Leaf[V]]) {
+	return r.Prefixed(nil, 0)
+}
+
+// Prefixed return an iterator on all the leaves matching the key/length
+// prefix in tree order. The next leaf is looked up before the current
+// leaf is returned, so the current leaf could be deleted during the
+// iteration.
+func (r *Tree[V])Prefixed(key *[]byte, length int16)(iter.Seq[*Leaf[V]]) {
+	return func(yield func(*Leaf[V])(bool)) {
+		var i Iterator[V]
+
+		i.init(r, key, length)
+		for i.Next() {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+/* Skip the children of the current node, the next node is the one
+ * following its subtree.
+ */
+func (i *Iterator[V])skip_children()() {
+	if i.node == nil || i.next_node == nil {
+		return
+	}
+	i.next_node = i.r.r2n(i.r.last_node(i.r.n2r(i.node)))
+	i.set_next()
+}
+
+// AllPrune is like All, but prune is called on each leaf before it is
+// returned. If prune returns true, the leaf is returned but its more
+// specific leaves are skipped.
+func (r *Tree[V])AllPrune(prune func(n *Leaf[V])(bool))(iter.Seq[*Leaf[V]]) {
+	return r.PrefixedPrune(nil, 0, prune)
+}
+
+// PrefixedPrune is like Prefixed, but prune is called on each leaf before
+// it is returned. If prune returns true, the leaf is returned but its more
+// specific leaves are skipped.
+func (r *Tree[V])PrefixedPrune(key *[]byte, length int16, prune func(n *Leaf[V])(bool))(iter.Seq[*Leaf[V]]) {
+	return func(yield func(*Leaf[V])(bool)) {
+		var i Iterator[V]
+		var n *Leaf[V]
+
+		i.init(r, key, length)
+		for i.Next() {
+			n = i.Get()
+			if prune(n) {
+				i.skip_children()
+			}
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// Backward return an iterator on all the leaves of the tree in reverse
+// tree order. The previous leaf is looked up before the current leaf is
+// returned, so the current leaf could be deleted during the iteration.
+func (r *Tree[V])Backward()(iter.Seq[*Leaf[V]]) {
+	return func(yield func(*Leaf[V])(bool)) {
+		var i Iterator[V]
+
+		i.init_reverse(r, nil, 0)
+		for i.Next() {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// Covering return an iterator on the leaves covering the key/length prefix,
+// from the least specific to the most specific. The next leaf is looked up
+// before the current leaf is returned, so the current leaf could be
+// deleted during the iteration.
+func (r *Tree[V])Covering(key *[]byte, length int16)(iter.Seq[*Leaf[V]]) {
+	return func(yield func(*Leaf[V])(bool)) {
+		var ref uint32
+		var next uint32
+
+		if length <= 0 {
+			return
+		}
+		ref = r.covering_from(r.Node, *key, length)
+		for ref != null {
+			next = r.covering_next(ref, *key, length)
+			if !yield(n2N[V](r.r2n(ref))) {
+				return
+			}
+			ref = next
+		}
+	}
+}
+
+// CoveringBackward return an iterator on the leaves covering the
+// key/length prefix, from the most specific to the least specific. The
+// first leaf is the result of LookupLonguest. The next leaf is looked up
+// before the current leaf is returned, so the current leaf could be
+// deleted during the iteration.
+func (r *Tree[V])CoveringBackward(key *[]byte, length int16)(iter.Seq[*Leaf[V]]) {
+	return func(yield func(*Leaf[V])(bool)) {
+		var ref uint32
+		var next uint32
+
+		if length <= 0 {
+			return
+		}
+		ref = r.covering_from(r.Node, *key, length)
+		if ref == null {
+			return
+		}
+		for {
+			next = r.covering_next(ref, *key, length)
+			if next == null {
+				break
+			}
+			ref = next
+		}
+		for ref != null {
+			next = r.parent_leaf(ref)
+			if !yield(n2N[V](r.r2n(ref))) {
+				return
+			}
+			ref = next
+		}
+	}
+}
+
+// Overlap is the relation between an overlapping leaf and the prefix
+// given to LookupOverlapping.
+type Overlap int
+
+const (
+	// OverlapCovering is a leaf less specific than the prefix.
+	OverlapCovering Overlap = iota
+	// OverlapEqual is the leaf of the prefix.
+	OverlapEqual
+	// OverlapCovered is a leaf more specific than the prefix.
+	OverlapCovered
+)
+
+// LookupOverlapping return an iterator on the leaves overlapping the
+// key/length prefix with their relation to the prefix. The leaves are
+// returned in tree order: the covering leaves from the least specific,
+// the equal leaf and the covered leaves. The next leaf is looked up before
+// the current leaf is returned, so the current leaf could be deleted
+// during the iteration.
+func (r *Tree[V])LookupOverlapping(key *[]byte, length int16)(iter.Seq2[*Leaf[V], Overlap]) {
+	return func(yield func(*Leaf[V], Overlap)(bool)) {
+		var n *Leaf[V]
+
+		for n = range r.Covering(key, length) {
+			if n.node.End + 1 == length {
+				break
+			}
+			if !yield(n, OverlapCovering) {
+				return
+			}
+		}
+		for n = range r.Prefixed(key, length) {
+			if n.node.End + 1 == length {
+				if !yield(n, OverlapEqual) {
+					return
+				}
+			} else if !yield(n, OverlapCovered) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,532 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "math/rand"
+import "net"
+import "testing"
+
+func TestIterSeq(t *testing.T) {
+	var r *Tree[int]
+	var n *Leaf[int]
+	var m *Leaf[int]
+	var list []*Leaf[int]
+	var it *Iterator[int]
+	var nw *net.IPNet
+	var v int
+	var key []byte
+	var count int
+	var i int
+
+	r = NewTree[int]()
+	for i = 0; i < 5000; i++ {
+		nw = &net.IPNet{}
+		nw.IP = net.IPv4(10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0)
+		nw.Mask = net.CIDRMask(16 + rand.Intn(9), 32)
+		r.IPv4Insert(nw, i)
+	}
+
+	/* All follows First/Next */
+	m = r.First()
+	for n = range r.All() {
+		if n != m {
+			t.Fatalf("All does not follow Next")
+		}
+		list = append(list, n)
+		m = r.Next(m)
+	}
+	if m != nil || len(list) != r.Len() {
+		t.Fatalf("Expect %d leaves, got %d", r.Len(), len(list))
+	}
+
+	/* Backward is the reverse of All */
+	i = len(list) - 1
+	for n = range r.Backward() {
+		if i < 0 || n != list[i] {
+			t.Fatalf("Backward is not the reverse of All at %d", i)
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("Backward stops at %d", i)
+	}
+
+	/* Prefixed follows NewIter */
+	key = []byte{10, 1, 0, 0}
+	it = r.NewIter(&key, 16)
+	for n = range r.Prefixed(&key, 16) {
+		if !it.Next() || it.Get() != n {
+			t.Fatalf("Prefixed does not follow NewIter")
+		}
+	}
+	if it.Next() {
+		t.Fatalf("Prefixed stops early")
+	}
+
+	/* Typed iterator and early break */
+	_, nw, _ = net.ParseCIDR("10.2.0.0/16")
+	for nw, v = range r.IPv4Prefixed(nw) {
+		if nw.IP[0] != 10 || nw.IP[1] != 2 || r.IPv4Get(nw).Data != v {
+			t.Fatalf("Unexpected network %s", nw)
+		}
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("Expect 10 networks, got %d", count)
+	}
+
+	/* No allocation */
+	if testing.AllocsPerRun(10, func() { for n = range r.Prefixed(&key, 16) {} }) != 0 {
+		t.Errorf("Prefixed allocates")
+	}
+
+	/* Delete during iteration */
+	for n = range r.All() {
+		r.Delete(n)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Expect empty tree, got %d leaves", r.Len())
+	}
+	for n = range r.Backward() {
+		t.Errorf("Unexpected leaf in empty tree")
+	}
+}
+
+func TestIterSeqString(t *testing.T) {
+	var r *Tree[int]
+	var s string
+	var v int
+	var got []string
+
+	r = NewTree[int]()
+	r.StringInsert("apple", 1)
+	r.StringInsert("apricot", 2)
+	r.StringInsert("banana", 3)
+	r.StringInsert("ap", 4)
+	for s, v = range r.StringPrefixed("ap") {
+		if r.StringGet(s).Data != v {
+			t.Errorf("Unexpected data for %s", s)
+		}
+		got = append(got, s)
+	}
+	if len(got) != 3 || got[0] != "ap" {
+		t.Errorf("Unexpected keys %v", got)
+	}
+}
+
+func TestIterPrune(t *testing.T) {
+	var r *Tree[int]
+	var n *Leaf[int]
+	var nw *net.IPNet
+	var key []byte
+	var got []int
+	var s string
+	var i int
+
+	r = NewTree[int]()
+	for i, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24", "10.1.2.0/24",
+	                          "10.2.0.0/16", "10.2.3.0/24", "11.0.0.0/8", "11.1.0.0/16"} {
+		_, nw, _ = net.ParseCIDR(s)
+		r.IPv4Insert(nw, i)
+	}
+
+	/* Prune 10.1.0.0/16 and 11.0.0.0/8 */
+	for n = range r.AllPrune(func(n *Leaf[int])(bool) { return n.Data == 1 || n.Data == 6 }) {
+		got = append(got, n.Data)
+	}
+	if len(got) != 5 || got[0] != 0 || got[1] != 1 || got[2] != 4 || got[3] != 5 || got[4] != 6 {
+		t.Errorf("Unexpected pruned leaves %v", got)
+	}
+
+	/* The browsing stays in the prefix after a prune */
+	got = nil
+	key = []byte{10, 0, 0, 0}
+	for n = range r.PrefixedPrune(&key, 8, func(n *Leaf[int])(bool) { return n.Data == 4 }) {
+		got = append(got, n.Data)
+	}
+	if len(got) != 5 || got[0] != 0 || got[3] != 3 || got[4] != 4 {
+		t.Errorf("Unexpected pruned leaves %v", got)
+	}
+
+	/* Delete the pruned leaf during the iteration */
+	got = nil
+	for n = range r.AllPrune(func(n *Leaf[int])(bool) { return n.Data == 1 }) {
+		got = append(got, n.Data)
+		if n.Data == 1 {
+			r.Delete(n)
+		}
+	}
+	if len(got) != 6 || got[1] != 1 || got[2] != 4 || r.Len() != 7 {
+		t.Errorf("Unexpected pruned leaves %v", got)
+	}
+}
+
+func TestReverseIter(t *testing.T) {
+	var r *Tree[int]
+	var list []*Leaf[int]
+	var n *Leaf[int]
+	var it *Iterator[int]
+	var nw *net.IPNet
+	var key []byte
+	var i int
+
+	r = NewTree[int]()
+	for i = 0; i < 5000; i++ {
+		nw = &net.IPNet{}
+		nw.IP = net.IPv4(10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0)
+		nw.Mask = net.CIDRMask(14 + rand.Intn(11), 32)
+		r.IPv4Insert(nw, i)
+	}
+
+	for _, key = range [][]byte{nil, []byte{10, 0, 0, 0}, []byte{10, 2, 0, 0}, []byte{10, 3, 128, 0}, []byte{11, 0, 0, 0}} {
+		for _, i = range []int{0, 14, 16, 17} {
+			if (key == nil) != (i == 0) {
+				continue
+			}
+
+			/* Forward list */
+			list = list[:0]
+			it = r.NewIter(&key, int16(i))
+			for it.Next() {
+				list = append(list, it.Get())
+			}
+
+			/* Reverse must give the same list in reverse order */
+			it = r.NewReverseIter(&key, int16(i))
+			for it.Next() {
+				n = it.Get()
+				if len(list) == 0 || list[len(list) - 1] != n {
+					t.Fatalf("Reverse iteration mismatch on %v/%d", key, i)
+				}
+				list = list[:len(list) - 1]
+			}
+			if len(list) != 0 {
+				t.Fatalf("Reverse iteration on %v/%d stops early", key, i)
+			}
+		}
+	}
+
+	/* IPv4 variant */
+	_, nw, _ = net.ParseCIDR("10.1.0.0/16")
+	i = 0
+	it = r.IPv4NewReverseIter(nw)
+	for it.Next() {
+		if !nw.Contains(it.Get().IPv4GetNet().IP) {
+			t.Fatalf("Unexpected network %s", it.Get().IPv4GetNet())
+		}
+		i++
+	}
+	it = r.IPv4NewIter(nw)
+	for it.Next() {
+		i--
+	}
+	if i != 0 {
+		t.Errorf("Reverse and forward iterations have different length")
+	}
+}
+
+
+func TestIterDelete(t *testing.T) {
+	var r *Tree[int]
+	var it *Iterator[int]
+	var keep map[int]bool
+	var nw *net.IPNet
+	var key []byte
+	var n *Leaf[int]
+	var count int
+	var i int
+
+	for _, i = range []int{0, 1} {
+		r = NewTree[int]()
+		keep = make(map[int]bool)
+		for count = 0; count < 5000; count++ {
+			nw = &net.IPNet{}
+			nw.IP = net.IPv4(10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0)
+			nw.Mask = net.CIDRMask(14 + rand.Intn(11), 32)
+			r.IPv4Insert(nw, count)
+		}
+		for n = range r.All() {
+			keep[n.Data] = true
+		}
+
+		/* Delete one leaf every two under 10.1.0.0/16 */
+		key = []byte{10, 1, 0, 0}
+		if i == 0 {
+			it = r.NewIter(&key, 16)
+		} else {
+			it = r.NewReverseIter(&key, 16)
+		}
+		count = 0
+		for it.Next() {
+			count++
+			if count % 2 == 0 {
+				delete(keep, it.Get().Data)
+				it.Delete()
+				if it.Get() != nil {
+					t.Fatalf("Expect nil leaf after delete")
+				}
+			}
+		}
+		if r.Len() != len(keep) {
+			t.Fatalf("Expect %d leaves, got %d", len(keep), r.Len())
+		}
+		for n = range r.All() {
+			if !keep[n.Data] {
+				t.Fatalf("Leaf %d not deleted", n.Data)
+			}
+		}
+
+		/* Remove all leaves under 10.1.0.0/16 */
+		count = r.RemoveIf(&key, 16, func(n *Leaf[int])(bool) { return true })
+		it = r.NewIter(&key, 16)
+		if it.Next() {
+			t.Fatalf("Expect no leaf under 10.1.0.0/16")
+		}
+		if r.Len() != len(keep) - count {
+			t.Fatalf("Expect %d leaves, got %d", len(keep) - count, r.Len())
+		}
+	}
+
+	/* RemoveIf with predicate on all the tree */
+	count = r.Len()
+	i = r.RemoveIf(nil, 0, func(n *Leaf[int])(bool) { return n.Data % 2 == 0 })
+	if r.Len() != count - i {
+		t.Fatalf("Expect %d leaves, got %d", count - i, r.Len())
+	}
+	for n = range r.All() {
+		if n.Data % 2 == 0 {
+			t.Fatalf("Leaf %d not removed", n.Data)
+		}
+	}
+}
+
+func TestCovering(t *testing.T) {
+	var r *Tree[int]
+	var path []*Leaf[int]
+	var got []*Leaf[int]
+	var n *Leaf[int]
+	var key []byte
+	var length int16
+	var i int
+	var j int
+
+	r = verify_random_tree()
+	for i = 0; i < 10000; i++ {
+		key = []byte{byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256))}
+		length = int16(1 + rand.Intn(32))
+		path = r.LookupLonguestPath(&key, length)
+
+		got = got[:0]
+		for n = range r.Covering(&key, length) {
+			got = append(got, n)
+		}
+		if len(got) != len(path) {
+			t.Fatalf("Expect %d covering leaves, got %d", len(path), len(got))
+		}
+		for j = range path {
+			if got[j] != path[j] {
+				t.Fatalf("Unexpected covering leaf at %d", j)
+			}
+		}
+
+		got = got[:0]
+		for n = range r.CoveringBackward(&key, length) {
+			got = append(got, n)
+		}
+		if len(got) != len(path) {
+			t.Fatalf("Expect %d covering leaves, got %d", len(path), len(got))
+		}
+		for j = range path {
+			if got[j] != path[len(path) - 1 - j] {
+				t.Fatalf("Unexpected backward covering leaf at %d", j)
+			}
+		}
+
+		n = r.LookupShortest(&key, length)
+		if (len(path) == 0 && n != nil) || (len(path) > 0 && n != path[0]) {
+			t.Fatalf("Unexpected shortest leaf")
+		}
+	}
+
+	/* No allocation */
+	if testing.AllocsPerRun(10, func() {
+		for n = range r.Covering(&key, length) {}
+		for n = range r.CoveringBackward(&key, length) {}
+	}) != 0 {
+		t.Errorf("Covering allocates")
+	}
+
+	/* Delete during the iteration */
+	key = []byte{10, 1, 2, 3}
+	r = NewTree[int]()
+	r.IPv4Insert(ipv4net("10.0.0.0/8"), 8)
+	r.IPv4Insert(ipv4net("10.1.0.0/16"), 16)
+	r.IPv4Insert(ipv4net("10.1.2.0/24"), 24)
+	r.IPv4Insert(ipv4net("10.1.3.0/24"), 25)
+	for n = range r.Covering(&key, 32) {
+		r.Delete(n)
+	}
+	if r.Len() != 1 || r.Verify() != nil {
+		t.Fatalf("Unexpected tree after delete")
+	}
+	r.IPv4Insert(ipv4net("10.0.0.0/8"), 8)
+	r.IPv4Insert(ipv4net("10.1.0.0/16"), 16)
+	r.IPv4Insert(ipv4net("10.1.2.0/24"), 24)
+	for n = range r.CoveringBackward(&key, 32) {
+		r.Delete(n)
+	}
+	if r.Len() != 1 || r.Verify() != nil {
+		t.Fatalf("Unexpected tree after delete")
+	}
+}
+
+func TestCoveringTyped(t *testing.T) {
+	var r *Tree[int]
+	var s *Tree[int]
+	var nw *net.IPNet
+	var str string
+	var v int
+	var got []int
+
+	r = NewTree[int]()
+	r.IPv4Insert(ipv4net("10.0.0.0/8"), 8)
+	r.IPv4Insert(ipv4net("10.1.0.0/16"), 16)
+	r.IPv4Insert(ipv4net("10.1.2.0/24"), 24)
+	r.IPv4Insert(ipv4net("10.2.0.0/16"), 17)
+	if r.IPv4LookupShortest(ipv4net("10.1.2.128/25")).Data != 8 {
+		t.Errorf("Unexpected shortest match")
+	}
+	if r.IPv4LookupShortest(ipv4net("11.0.0.0/8")) != nil {
+		t.Errorf("Unexpected shortest match")
+	}
+	for nw, v = range r.IPv4Covering(ipv4net("10.1.2.128/25")) {
+		if r.IPv4Get(nw).Data != v {
+			t.Errorf("Unexpected data for %s", nw)
+		}
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != 8 || got[2] != 24 {
+		t.Errorf("Unexpected covering %v", got)
+	}
+	got = nil
+	for _, v = range r.IPv4CoveringBackward(ipv4net("10.1.2.128/25")) {
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != 24 || got[2] != 8 {
+		t.Errorf("Unexpected covering %v", got)
+	}
+
+	s = NewTree[int]()
+	s.StringInsert("a", 1)
+	s.StringInsert("ap", 2)
+	s.StringInsert("apple", 5)
+	s.StringInsert("apricot", 7)
+	if s.StringLookupShortest("applesauce").Data != 1 {
+		t.Errorf("Unexpected shortest match")
+	}
+	got = nil
+	for str, v = range s.StringCovering("applesauce") {
+		if len(str) != v {
+			t.Errorf("Unexpected data for %s", str)
+		}
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 5 {
+		t.Errorf("Unexpected covering %v", got)
+	}
+	got = nil
+	for _, v = range s.StringCoveringBackward("applesauce") {
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != 5 || got[2] != 1 {
+		t.Errorf("Unexpected covering %v", got)
+	}
+}
+
+func TestLookupOverlapping(t *testing.T) {
+	var r *Tree[int]
+	var path []*Leaf[int]
+	var want []*Leaf[int]
+	var got []*Leaf[int]
+	var kinds []Overlap
+	var n *Leaf[int]
+	var o Overlap
+	var it *Iterator[int]
+	var key []byte
+	var length int16
+	var i int
+	var j int
+
+	r = verify_random_tree()
+	for i = 0; i < 2000; i++ {
+		key = []byte{byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256))}
+		length = int16(1 + rand.Intn(32))
+
+		/* Expected result */
+		want = want[:0]
+		path = r.LookupLonguestPath(&key, length)
+		for _, n = range path {
+			if n.node.End + 1 < length {
+				want = append(want, n)
+			}
+		}
+		it = r.NewIter(&key, length)
+		for it.Next() {
+			want = append(want, it.Get())
+		}
+
+		got = got[:0]
+		kinds = kinds[:0]
+		for n, o = range r.LookupOverlapping(&key, length) {
+			got = append(got, n)
+			kinds = append(kinds, o)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Expect %d overlapping leaves, got %d", len(want), len(got))
+		}
+		for j = range want {
+			if got[j] != want[j] {
+				t.Fatalf("Unexpected overlapping leaf at %d", j)
+			}
+			switch {
+			case got[j].node.End + 1 < length:
+				o = OverlapCovering
+			case got[j].node.End + 1 == length:
+				o = OverlapEqual
+			default:
+				o = OverlapCovered
+			}
+			if kinds[j] != o {
+				t.Fatalf("Expect relation %d, got %d", o, kinds[j])
+			}
+		}
+	}
+
+	/* IPv4 */
+	r = NewTree[int]()
+	r.IPv4Insert(ipv4net("10.0.0.0/8"), 8)
+	r.IPv4Insert(ipv4net("10.1.0.0/16"), 16)
+	r.IPv4Insert(ipv4net("10.1.2.0/24"), 24)
+	r.IPv4Insert(ipv4net("10.1.3.0/24"), 24)
+	r.IPv4Insert(ipv4net("10.2.0.0/16"), 17)
+	kinds = kinds[:0]
+	for n, o = range r.IPv4LookupOverlapping(ipv4net("10.1.0.0/16")) {
+		if n.Data == 17 {
+			t.Errorf("Unexpected leaf %s", n.IPv4GetNet())
+		}
+		kinds = append(kinds, o)
+	}
+	if len(kinds) != 4 || kinds[0] != OverlapCovering || kinds[1] != OverlapEqual || kinds[2] != OverlapCovered || kinds[3] != OverlapCovered {
+		t.Errorf("Unexpected relations %v", kinds)
+	}
+	kinds = kinds[:0]
+	for _, o = range r.IPv4LookupOverlapping(ipv4net("10.1.0.0/17")) {
+		kinds = append(kinds, o)
+	}
+	if len(kinds) != 4 || kinds[1] != OverlapCovering || kinds[2] != OverlapCovered {
+		t.Errorf("Unexpected relations %v", kinds)
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package radix
+
+import "io"
+import "os"
+
+/* No mmap available, the file is loaded in memory */
+func mmap_file(f *os.File, size int)([]byte, error) {
+	var data []byte
+	var err error
+
+	data = make([]byte, size)
+	_, err = io.ReadFull(f, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func munmap_file(data []byte)(error) {
+	return nil
+}
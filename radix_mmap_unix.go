@@ -0,0 +1,16 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package radix
+
+import "os"
+import "syscall"
+
+func mmap_file(f *os.File, size int)([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap_file(data []byte)(error) {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "net/netip"
+
+/* Convert address to key using buffer provided by the caller, so
+ * the key is allocated on the caller stack. IPv4 addresses use 4
+ * bytes like the IPv4 functions and IPv6 addresses use 16 bytes like
+ * the IPv6 functions, so the netip API could be mixed with the net
+ * API of the same family. IPv4 mapped IPv6 addresses are keep as IPv6.
+ */
+func addr_to_key(addr netip.Addr, buf *[16]byte)([]byte) {
+	var b4 [4]byte
+
+	if addr.Is4() {
+		b4 = addr.As4()
+		copy(buf[:], b4[:])
+		return buf[:4]
+	}
+	*buf = addr.As16()
+	return buf[:]
+}
+
+/* The IPv4 and IPv6 keys share the same bits, so 1.2.3.4/32 and
+ * 102:304::/32 are the same prefix. A tree indexes only one family,
+ * return false if the key is not of the family of the tree.
+ */
+func (r *Tree[V])same_family(key []byte)(bool) {
+	if r.Node == null {
+		return true
+	}
+	return len(r.r2n(r.Node).Bytes) == len(key)
+}
+
+/* Return key and length of the prefix. Return a 0 length if
+ * the prefix is invalid.
+ */
+func prefix_to_key(prefix netip.Prefix, buf *[16]byte)([]byte, int16) {
+	if !prefix.IsValid() {
+		return nil, 0
+	}
+	return addr_to_key(prefix.Addr(), buf), int16(prefix.Bits())
+}
+
+// AddrLookupLonguest get a IPv4 or IPv6 address and return the leaf which
+// match the longest part of the address. Return nil if none match.
+func (r *Tree[V])AddrLookupLonguest(addr netip.Addr)(*Leaf[V]) {
+	var buf [16]byte
+	var key []byte
+
+	if !addr.IsValid() {
+		return nil
+	}
+	key = addr_to_key(addr, &buf)
+	if !r.same_family(key) {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.LookupLonguest(&key, int16(addr.BitLen()))
+}
+
+// PrefixLookupLonguest get a IPv4 or IPv6 prefix and return the leaf which
+// match the longest part of the prefix. Return nil if none match.
+func (r *Tree[V])PrefixLookupLonguest(prefix netip.Prefix)(*Leaf[V]) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = prefix_to_key(prefix, &buf)
+	if length == 0 || !r.same_family(key) {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.LookupLonguest(&key, length)
+}
+
+// PrefixLookupLonguestPath take the radix tree and a IPv4 or IPv6 prefix,
+// return the list of all leaf matching the prefix. If none match, return nil
+func (r *Tree[V])PrefixLookupLonguestPath(prefix netip.Prefix)([]*Leaf[V]) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = prefix_to_key(prefix, &buf)
+	if length == 0 || !r.same_family(key) {
+		return make([]*Leaf[V], 0)
+	}
+
+	/* Perform lookup */
+	return r.LookupLonguestPath(&key, length)
+}
+
+// PrefixGet gets a IPv4 or IPv6 prefix and return exact match of the prefix.
+// Exact match is a node wich match the prefix bit and the length.
+func (r *Tree[V])PrefixGet(prefix netip.Prefix)(*Leaf[V]) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = prefix_to_key(prefix, &buf)
+	if length == 0 || !r.same_family(key) {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.Get(&key, length)
+}
+
+// PrefixInsert IPv4 or IPv6 prefix in the tree. The tree accept only unique
+// value, if the prefix already exists in the tree, return existing leaf,
+// otherwaise return nil. The IPv4 and IPv6 keys share the same bits, so a
+// tree indexes only one address family: the prefixes of the other family
+// are rejected and return nil and false, and the lookups of the other
+// family does not match.
+func (r *Tree[V])PrefixInsert(prefix netip.Prefix, data V)(*Leaf[V], bool) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited. The
+	 * masked prefix is used to store a clean key.
+	 */
+	key, length = prefix_to_key(prefix.Masked(), &buf)
+	if length == 0 || !r.same_family(key) {
+		return nil, false
+	}
+
+	/* Perform insert */
+	return r.Insert(&key, length, data)
+}
+
+// PrefixDelete lookup IPv4 or IPv6 prefix and remove it. does nothing
+// if the prefix not exists.
+func (r *Tree[V])PrefixDelete(prefix netip.Prefix)() {
+	var buf [16]byte
+	var node *Leaf[V]
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = prefix_to_key(prefix, &buf)
+	if length == 0 || !r.same_family(key) {
+		return
+	}
+
+	/* Perform lookup */
+	node = r.Get(&key, length)
+	if node == nil {
+		return
+	}
+
+	/* Delete entry */
+	r.Delete(node)
+}
+
+// Prefix convert node key/length prefix to netip.Prefix. The address
+// family is deduced from the key length, return an invalid prefix if
+// the key is not a network.
+func (n *Leaf[V])Prefix()(netip.Prefix) {
+	var addr netip.Addr
+	var b4 [4]byte
+	var b16 [16]byte
+
+	switch len(n.node.Bytes) {
+	case 4:
+		copy(b4[:], n.node.Bytes)
+		addr = netip.AddrFrom4(b4)
+	case 16:
+		copy(b16[:], n.node.Bytes)
+		addr = netip.AddrFrom16(b16)
+	default:
+		return netip.Prefix{}
+	}
+	return netip.PrefixFrom(addr, int(n.node.End) + 1).Masked()
+}
+
+// PrefixNewIter return struct Iter for browsing all nodes there children
+// match the IPv4 or IPv6 prefix.
+func (r *Tree[V])PrefixNewIter(prefix netip.Prefix)(*Iterator[V]) {
+	var buf [16]byte
+	var length int16
+	var key []byte
+
+	key, length = prefix_to_key(prefix, &buf)
+	if key == nil || !r.same_family(key) {
+		return &Iterator[V]{}
+	}
+	return r.NewIter(&key, length)
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "net"
+import "net/netip"
+import "testing"
+
+func TestRadixNetip(t *testing.T) {
+	var r *Radix
+	var nw *net.IPNet
+	var n *Node
+	var ns []*Node
+	var it *Iter
+	var count int
+	var allocs float64
+	var s string
+
+	r = NewRadix()
+
+	for _, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16", "192.168.0.0/16"} {
+		r.PrefixInsert(netip.MustParsePrefix(s), s)
+	}
+
+	/* netip API and net API share the same keys */
+	_, nw, _ = net.ParseCIDR("10.1.0.0/16")
+	n = r.IPv4Get(nw)
+	if n == nil || n.Data.(string) != "10.1.0.0/16" {
+		t.Errorf("10.1.0.0/16 should be found with IPv4Get")
+	}
+	if n.Prefix() != netip.MustParsePrefix("10.1.0.0/16") {
+		t.Errorf("Expect prefix 10.1.0.0/16, got %s", n.Prefix())
+	}
+
+	/* Insert not masked prefix store masked prefix */
+	r.PrefixInsert(netip.MustParsePrefix("172.16.3.4/12"), "172.16.0.0/12")
+	n = r.PrefixGet(netip.MustParsePrefix("172.16.0.0/12"))
+	if n == nil || n.Prefix().String() != "172.16.0.0/12" {
+		t.Errorf("172.16.0.0/12 should be found")
+	}
+
+	/* Longest match */
+	n = r.AddrLookupLonguest(netip.MustParseAddr("10.2.3.4"))
+	if n == nil || n.Data.(string) != "10.2.0.0/16" {
+		t.Errorf("10.2.3.4 should match 10.2.0.0/16")
+	}
+	n = r.AddrLookupLonguest(netip.MustParseAddr("10.3.3.4"))
+	if n == nil || n.Data.(string) != "10.0.0.0/8" {
+		t.Errorf("10.3.3.4 should match 10.0.0.0/8")
+	}
+	n = r.AddrLookupLonguest(netip.Addr{})
+	if n != nil {
+		t.Errorf("invalid address should not match")
+	}
+
+	/* Path */
+	ns = r.PrefixLookupLonguestPath(netip.MustParsePrefix("10.1.2.0/24"))
+	if len(ns) != 2 {
+		t.Errorf("Should have length of 2, got %d", len(ns))
+	}
+
+	/* Iter */
+	it = r.PrefixNewIter(netip.MustParsePrefix("10.0.0.0/8"))
+	for it.Next() {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Should iterate over 3 entries, got %d", count)
+	}
+
+	/* Delete */
+	r.PrefixDelete(netip.MustParsePrefix("10.1.0.0/16"))
+	if r.PrefixGet(netip.MustParsePrefix("10.1.0.0/16")) != nil {
+		t.Errorf("10.1.0.0/16 should be deleted")
+	}
+
+	/* IPv6 */
+	r = NewRadix()
+	r.PrefixInsert(netip.MustParsePrefix("2001:db8::/32"), "2001:db8::/32")
+	n = r.AddrLookupLonguest(netip.MustParseAddr("2001:db8::1"))
+	if n == nil || n.Prefix().String() != "2001:db8::/32" {
+		t.Errorf("2001:db8::1 should match 2001:db8::/32")
+	}
+
+	/* Lookup should not allocate memory */
+	allocs = testing.AllocsPerRun(100, func() {
+		r.AddrLookupLonguest(netip.MustParseAddr("2001:db8::1"))
+		r.PrefixGet(netip.MustParsePrefix("2001:db8::/32"))
+	})
+	if allocs != 0 {
+		t.Errorf("Expect no allocation, got %f", allocs)
+	}
+}
+
+func TestRadixNetipFamily(t *testing.T) {
+	var r *Tree[string]
+	var n *Leaf[string]
+	var ok bool
+
+	/* The tree takes the family of its first prefix */
+	r = NewTree[string]()
+	_, ok = r.PrefixInsert(netip.MustParsePrefix("1.2.3.4/32"), "v4")
+	if !ok {
+		t.Fatalf("1.2.3.4/32 should be inserted")
+	}
+	n, ok = r.PrefixInsert(netip.MustParsePrefix("102:304::/32"), "v6")
+	if n != nil || ok {
+		t.Errorf("IPv6 prefix should be rejected in IPv4 tree")
+	}
+	if r.AddrLookupLonguest(netip.MustParseAddr("102:304::1")) != nil {
+		t.Errorf("IPv6 address should not match IPv4 prefix")
+	}
+	if r.PrefixGet(netip.MustParsePrefix("102:304::/32")) != nil {
+		t.Errorf("IPv6 prefix should not match IPv4 prefix")
+	}
+	if r.PrefixLookupLonguest(netip.MustParsePrefix("102:304::/48")) != nil {
+		t.Errorf("IPv6 prefix should not match IPv4 prefix")
+	}
+	if len(r.PrefixLookupLonguestPath(netip.MustParsePrefix("102:304::/48"))) != 0 {
+		t.Errorf("IPv6 prefix should not match IPv4 prefix")
+	}
+	if r.PrefixNewIter(netip.MustParsePrefix("102::/16")).Next() {
+		t.Errorf("IPv6 prefix should not browse IPv4 prefixes")
+	}
+	r.PrefixDelete(netip.MustParsePrefix("102:304::/32"))
+	if r.Len() != 1 {
+		t.Errorf("IPv6 delete should not remove IPv4 prefix")
+	}
+
+	/* Same in an IPv6 tree */
+	r = NewTree[string]()
+	r.PrefixInsert(netip.MustParsePrefix("102:304::/32"), "v6")
+	_, ok = r.PrefixInsert(netip.MustParsePrefix("1.2.3.4/32"), "v4")
+	if ok {
+		t.Errorf("IPv4 prefix should be rejected in IPv6 tree")
+	}
+	if r.AddrLookupLonguest(netip.MustParseAddr("1.2.3.4")) != nil {
+		t.Errorf("IPv4 address should not match IPv6 prefix")
+	}
+	n = r.AddrLookupLonguest(netip.MustParseAddr("102:304::1"))
+	if n == nil || n.Data != "v6" {
+		t.Errorf("102:304::1 should match 102:304::/32")
+	}
+
+	/* The family is free again once the tree is empty */
+	r.PrefixDelete(netip.MustParsePrefix("102:304::/32"))
+	_, ok = r.PrefixInsert(netip.MustParsePrefix("1.2.3.4/32"), "v4")
+	if !ok {
+		t.Errorf("1.2.3.4/32 should be inserted in empty tree")
+	}
+}
@@ -7,27 +7,38 @@ import "unsafe"
 const kind_node = 0
 const kind_leaf = 1
 
+const chunk_first = 64
+const chunk_max = 65536
+
 type node_chunk struct {
-	nodes [65536]node
+	nodes []node
+	count []uint32
 	ptr uintptr
+	gen uint64
 }
 
 type node_pool struct {
 	free int
 	capacity int
+	reclaimed int
+	first int
 	pool []*node_chunk
 	next uint32
 }
 
-type leaf_chunk struct {
-	nodes [65536]Node
+type leaf_chunk[V any] struct {
+	nodes []Leaf[V]
+	count []uint32
 	ptr uintptr
+	gen uint64
 }
 
-type leaf_pool struct {
+type leaf_pool[V any] struct {
 	free int
 	capacity int
-	pool []*leaf_chunk
+	reclaimed int
+	first int
+	pool []*leaf_chunk[V]
 	next uint32
 }
 
@@ -38,11 +49,36 @@ type ptr_range struct {
 	kind int
 }
 
+/* Return the number of entries of the chunk index. The chunks grow
+ * from the first size up to 65536 entries, so a small tree does not
+ * allocate 65536 entries. The offset in a chunk still use 16 bits,
+ * so the reference encoding does not depend on the chunk size.
+ */
+func chunk_size(first int, index int)(int) {
+	var size int
+
+	size = first
+	if size == 0 {
+		size = chunk_first
+	}
+	for ; index > 0 && size < chunk_max; index-- {
+		if size < 256 {
+			size *= 4
+		} else {
+			size *= 16
+		}
+	}
+	if size > chunk_max {
+		size = chunk_max
+	}
+	return size
+}
+
 func is_leaf(ref uint32)(bool) {
 	return (ref & 0x80000000) != 0
 }
 
-func (r *Radix)node_alloc()(*node) {
+func (r *Tree[V])node_alloc()(*node) {
 	var n *node
 
 	if r.node.free == 0 {
@@ -54,24 +90,25 @@ func (r *Radix)node_alloc()(*node) {
 	return n
 }
 
-func (r *Radix)leaf_alloc()(*Node) {
-	var n *Node
+func (r *Tree[V])leaf_alloc()(*Leaf[V]) {
+	var n *Leaf[V]
 
 	if r.leaf.free == 0 {
 		r.leaf_growth()
 	}
 	r.leaf.free--
-	n = n2N(r.r2n(r.leaf.next))
+	n = n2N[V](r.r2n(r.leaf.next))
 	r.leaf.next = n.node.Left
 	return n
 }
 
-func (r *Radix)free(n *node)() {
-	var leaf *Node
+func (r *Tree[V])free(n *node)() {
+	var leaf *Leaf[V]
+	var zero V
 
 	if is_leaf(r.n2r(n)) {
-		leaf = n2N(n)
-		leaf.Data = nil
+		leaf = n2N[V](n)
+		leaf.Data = zero
 		leaf.node.Bytes = ""
 		leaf.node.Parent = null
 		leaf.node.Left = r.leaf.next
@@ -89,7 +126,7 @@ func (r *Radix)free(n *node)() {
 }
 
 /* reference to node */
-func (r *Radix)r2n(v uint32)(*node) {
+func (r *Tree[V])r2n(v uint32)(*node) {
 	if v == null {
 		return nil
 	}
@@ -100,19 +137,27 @@ func (r *Radix)r2n(v uint32)(*node) {
 	}
 }
 
-func (r *Radix)node_growth() {
+func (r *Tree[V])node_growth() {
 	var c *node_chunk
+	var size int
 	var i int
 
 	if len(r.node.pool) >= 32768 {
 		panic("reach the maximum number of node pools allowed")
 	}
-	c = &node_chunk{}
+	size = chunk_size(r.node.first, len(r.node.pool))
+	c = &node_chunk{nodes: make([]node, size)}
 	c.ptr = (uintptr)(unsafe.Pointer(&c.nodes[0]))
+	if r.counted {
+		c.count = make([]uint32, size)
+	}
+	if r.cow != nil {
+		c.gen = r.cow.gen
+	}
 	r.node.pool = append(r.node.pool, c)
-	r.node.free += 65536
-	r.node.capacity += 65536
-	r.add_range(c.ptr, (uintptr)(unsafe.Pointer(&c.nodes[65536 - 1])), len(r.node.pool) - 1, kind_node)
+	r.node.free += size
+	r.node.capacity += size
+	r.add_range(c.ptr, (uintptr)(unsafe.Pointer(&c.nodes[size - 1])), len(r.node.pool) - 1, kind_node)
 	for i, _ = range c.nodes {
 		/* first node of the first list the NULL node, so it never be used.
 		 * to make the code simpler, it is allocated, but it is never set
@@ -128,19 +173,27 @@ func (r *Radix)node_growth() {
 	}
 }
 
-func (r *Radix)leaf_growth() {
-	var c *leaf_chunk
+func (r *Tree[V])leaf_growth() {
+	var c *leaf_chunk[V]
+	var size int
 	var i int
 
 	if len(r.leaf.pool) >= 32768 {
 		panic("reach the maximum number of node pools allowed")
 	}
-	c = &leaf_chunk{}
+	size = chunk_size(r.leaf.first, len(r.leaf.pool))
+	c = &leaf_chunk[V]{nodes: make([]Leaf[V], size)}
 	c.ptr = (uintptr)(unsafe.Pointer(&c.nodes[0]))
+	if r.counted {
+		c.count = make([]uint32, size)
+	}
+	if r.cow != nil {
+		c.gen = r.cow.gen
+	}
 	r.leaf.pool = append(r.leaf.pool, c)
-	r.leaf.free += 65536
-	r.leaf.capacity += 65536
-	r.add_range(c.ptr, (uintptr)(unsafe.Pointer(&c.nodes[65536 - 1])), len(r.leaf.pool) - 1, kind_leaf)
+	r.leaf.free += size
+	r.leaf.capacity += size
+	r.add_range(c.ptr, (uintptr)(unsafe.Pointer(&c.nodes[size - 1])), len(r.leaf.pool) - 1, kind_leaf)
 	for i, _ = range c.nodes {
 		c.nodes[i].node.Left = r.leaf.next
 		r.leaf.next = r.n2r(&c.nodes[i].node)
@@ -148,7 +201,7 @@ func (r *Radix)leaf_growth() {
 }
 
 /* if insert a range which overlap existing range, it panic */
-func (r *Radix)add_range(start uintptr, end uintptr, index int, kind int) {
+func (r *Tree[V])add_range(start uintptr, end uintptr, index int, kind int) {
 	var left int
 	var right int
 	var pivot int
@@ -186,16 +239,16 @@ func (r *Radix)add_range(start uintptr, end uintptr, index int, kind int) {
 }
 
 /* Give a node and return its reference. If the node is not
- * from a local pool return 0.
+ * from a local pool return 0. The offset is computed from the range,
+ * so a node of a chunk replaced by a copy keeps its reference.
  */
-func (r *Radix)n2r(n *node)(uint32) {
+func (r *Tree[V])n2r(n *node)(uint32) {
 	var left int
 	var right int
 	var pivot int
 	var index int
 	var p uintptr
-	var cn *node_chunk
-	var cl *leaf_chunk
+	var start uintptr
 	var kind int
 
 	p = uintptr(unsafe.Pointer(n))
@@ -207,6 +260,7 @@ func (r *Radix)n2r(n *node)(uint32) {
 		if left == right {
 			index = r.ptr_range[left].index
 			kind = r.ptr_range[left].kind
+			start = r.ptr_range[left].start
 			break
 		}
 		pivot = (left + right) / 2
@@ -217,6 +271,7 @@ func (r *Radix)n2r(n *node)(uint32) {
 		} else {
 			index = r.ptr_range[pivot].index
 			kind = r.ptr_range[pivot].kind
+			start = r.ptr_range[pivot].start
 			break
 		}
 		if left > right {
@@ -224,10 +279,332 @@ func (r *Radix)n2r(n *node)(uint32) {
 		}
 	}
 	if kind == kind_node {
-		cn = r.node.pool[index]
-		return (uint32(index) << 16) | (uint32(p - cn.ptr) / node_sz)
+		return (uint32(index) << 16) | (uint32(p - start) / node_sz)
+	} else {
+		return 0x80000000 | (uint32(index) << 16) | (uint32(p - start) / leaf_size[V]())
+	}
+}
+
+/* Copy the subtree counts of a chunk, reuse dst if possible */
+func copy_count(dst []uint32, src []uint32)([]uint32) {
+	if src == nil {
+		return nil
+	}
+	if len(dst) != len(src) {
+		dst = make([]uint32, len(src))
+	}
+	copy(dst, src)
+	return dst
+}
+
+/* Copy the tree src in r. The references are preserved, so the copy
+ * is done chunk by chunk without browsing the tree. The chunks already
+ * allocated in r are reused.
+ */
+func (r *Tree[V])copy_from(src *Tree[V]) {
+	var i int
+	var cn *node_chunk
+	var cl *leaf_chunk[V]
+
+	r.Node = src.Node
+	r.length = src.length
+	r.codec = src.codec
+	r.counted = src.counted
+
+	/* Copy node chunks. The chunks of r are reused only if they
+	 * have the same size.
+	 */
+	for i = range src.node.pool {
+		if i >= len(r.node.pool) {
+			r.node.pool = append(r.node.pool, nil)
+		}
+		cn = r.node.pool[i]
+		if cn == nil || len(cn.nodes) != len(src.node.pool[i].nodes) {
+			cn = &node_chunk{nodes: make([]node, len(src.node.pool[i].nodes))}
+			cn.ptr = (uintptr)(unsafe.Pointer(&cn.nodes[0]))
+			r.node.pool[i] = cn
+		}
+		copy(cn.nodes, src.node.pool[i].nodes)
+		cn.count = copy_count(cn.count, src.node.pool[i].count)
+	}
+	for i = len(src.node.pool); i < len(r.node.pool); i++ {
+		r.node.pool[i] = nil
+	}
+	r.node.pool = r.node.pool[:len(src.node.pool)]
+	r.node.free = src.node.free
+	r.node.capacity = src.node.capacity
+	r.node.first = src.node.first
+	r.node.next = src.node.next
+
+	/* Copy leaf chunks */
+	for i = range src.leaf.pool {
+		if i >= len(r.leaf.pool) {
+			r.leaf.pool = append(r.leaf.pool, nil)
+		}
+		cl = r.leaf.pool[i]
+		if cl == nil || len(cl.nodes) != len(src.leaf.pool[i].nodes) {
+			cl = &leaf_chunk[V]{nodes: make([]Leaf[V], len(src.leaf.pool[i].nodes))}
+			cl.ptr = (uintptr)(unsafe.Pointer(&cl.nodes[0]))
+			r.leaf.pool[i] = cl
+		}
+		copy(cl.nodes, src.leaf.pool[i].nodes)
+		cl.count = copy_count(cl.count, src.leaf.pool[i].count)
+	}
+	for i = len(src.leaf.pool); i < len(r.leaf.pool); i++ {
+		r.leaf.pool[i] = nil
+	}
+	r.leaf.pool = r.leaf.pool[:len(src.leaf.pool)]
+	r.leaf.free = src.leaf.free
+	r.leaf.capacity = src.leaf.capacity
+	r.leaf.first = src.leaf.first
+	r.leaf.next = src.leaf.next
+
+	r.rebuild_ranges()
+}
+
+// Clone return an independent copy of the tree. The pools are copied
+// chunk by chunk and the references are preserved, so the entries are not
+// inserted again. If dup is not nil, it is called with the data of each
+// leaf and its result is stored in the new tree, this allows a deep copy
+// of data containing pointers, maps or slices. Otherwise the data is
+// copied as is.
+func (r *Tree[V])Clone(dup func(data V)(V))(*Tree[V]) {
+	var t *Tree[V]
+	var n *Leaf[V]
+
+	t = NewTree[V]()
+	t.copy_from(r)
+	if dup != nil {
+		for n = range t.All() {
+			n.Data = dup(n.Data)
+		}
+	}
+	return t
+}
+
+/* Rebuild pointer ranges from the pools */
+func (r *Tree[V])rebuild_ranges() {
+	var i int
+	var cn *node_chunk
+	var cl *leaf_chunk[V]
+
+	r.ptr_range = r.ptr_range[:0]
+	for i, cn = range r.node.pool {
+		r.add_range(cn.ptr, (uintptr)(unsafe.Pointer(&cn.nodes[len(cn.nodes) - 1])), i, kind_node)
+	}
+	for i, cl = range r.leaf.pool {
+		r.add_range(cl.ptr, (uintptr)(unsafe.Pointer(&cl.nodes[len(cl.nodes) - 1])), i, kind_leaf)
+	}
+}
+
+/* Append the references of the node and its children in tree order */
+func (r *Tree[V])live_refs(ref uint32, refs []uint32)([]uint32) {
+	var n *node
+
+	if ref == null {
+		return refs
+	}
+	refs = append(refs, ref)
+	n = r.r2n(ref)
+	refs = r.live_refs(n.Left, refs)
+	return r.live_refs(n.Right, refs)
+}
+
+/* Move the node or leaf ref in the free slot to, and update the
+ * references of its parent and its children.
+ */
+func (r *Tree[V])move(ref uint32, to uint32) {
+	var n *node
+	var p *node
+
+	if is_leaf(ref) {
+		*n2N[V](r.r2n(to)) = *n2N[V](r.r2n(ref))
+	} else {
+		*r.r2n(to) = *r.r2n(ref)
+	}
+	n = r.r2n(to)
+	if n.Parent == null {
+		r.Node = to
 	} else {
-		cl = r.leaf.pool[index]
-		return 0x80000000 | (uint32(index) << 16) | (uint32(p - cl.ptr) / leaf_sz)
+		p = r.r2n(n.Parent)
+		if p.Left == ref {
+			p.Left = to
+		} else {
+			p.Right = to
+		}
+	}
+	if n.Left != null {
+		r.r2n(n.Left).Parent = to
+	}
+	if n.Right != null {
+		r.r2n(n.Right).Parent = to
+	}
+}
+
+const slot_free = 0
+const slot_used = 1
+const slot_none = 2
+
+/* Compute the number of chunks required for live entries and the
+ * state of the slots of these chunks, indexed by reference. The
+ * slots beyond the chunk size and the reserved null slot are
+ * marked as slot_none.
+ */
+func compact_used(refs []uint32, live int, reserved int, leaf bool, first int)(int, []byte) {
+	var chunks int
+	var capacity int
+	var used []byte
+	var ref uint32
+	var index int
+	var i int
+
+	if live == 0 {
+		return 0, nil
+	}
+	for capacity < live + reserved {
+		capacity += chunk_size(first, chunks)
+		chunks++
+	}
+	used = make([]byte, chunks << 16)
+	for i = range used {
+		if i & 0xffff >= chunk_size(first, i >> 16) {
+			used[i] = slot_none
+		}
+	}
+	if reserved != 0 {
+		used[0] = slot_none
+	}
+	for _, ref = range refs {
+		if is_leaf(ref) != leaf {
+			continue
+		}
+		index = int(ref & 0x7fffffff)
+		if index < len(used) {
+			used[index] = slot_used
+		}
+	}
+	return chunks, used
+}
+
+// Compact move the nodes and the leaves stored in the last chunks of the
+// pools to the free slots of the first chunks, and release the chunks no
+// longer used. It return the number of bytes reclaimed. The leaves are
+// moved, so all the *Leaf and Iterator obtained before the call are
+// invalid after the call.
+func (r *Tree[V])Compact()(int) {
+	var refs []uint32
+	var ref uint32
+	var to uint32
+	var node_used []byte
+	var leaf_used []byte
+	var node_chunks int
+	var leaf_chunks int
+	var node_live int
+	var leaf_live int
+	var node_pos int
+	var leaf_pos int
+	var reclaimed_node int
+	var reclaimed_leaf int
+	var n *node
+	var leaf *Leaf[V]
+	var i int
+
+	if r.cow != nil {
+		r.cow_all()
 	}
+	refs = r.live_refs(r.Node, nil)
+	for _, ref = range refs {
+		if is_leaf(ref) {
+			leaf_live++
+		} else {
+			node_live++
+		}
+	}
+	node_chunks, node_used = compact_used(refs, node_live, 1, false, r.node.first)
+	leaf_chunks, leaf_used = compact_used(refs, leaf_live, 0, true, r.leaf.first)
+	if node_chunks == len(r.node.pool) && leaf_chunks == len(r.leaf.pool) {
+		return 0
+	}
+
+	/* Move the entries stored in released chunks */
+	for _, ref = range refs {
+		if is_leaf(ref) {
+			if int(ref & 0x7fffffff) < len(leaf_used) {
+				continue
+			}
+			for leaf_used[leaf_pos] != slot_free {
+				leaf_pos++
+			}
+			leaf_used[leaf_pos] = slot_used
+			to = 0x80000000 | uint32(leaf_pos)
+		} else {
+			if int(ref) < len(node_used) {
+				continue
+			}
+			for node_used[node_pos] != slot_free {
+				node_pos++
+			}
+			node_used[node_pos] = slot_used
+			to = uint32(node_pos)
+		}
+		r.move(ref, to)
+	}
+
+	/* Release chunks */
+	for i = node_chunks; i < len(r.node.pool); i++ {
+		reclaimed_node += int(unsafe.Sizeof(node_chunk{})) + len(r.node.pool[i].nodes) * int(node_sz) + len(r.node.pool[i].count) * 4
+		r.node.pool[i] = nil
+	}
+	r.node.pool = r.node.pool[:node_chunks]
+	for i = leaf_chunks; i < len(r.leaf.pool); i++ {
+		reclaimed_leaf += int(unsafe.Sizeof(leaf_chunk[V]{})) + len(r.leaf.pool[i].nodes) * int(leaf_size[V]()) + len(r.leaf.pool[i].count) * 4
+		r.leaf.pool[i] = nil
+	}
+	r.leaf.pool = r.leaf.pool[:leaf_chunks]
+
+	/* Rebuild free lists */
+	r.node.next = null
+	r.node.free = 0
+	r.node.capacity = 0
+	for i = len(node_used) - 1; i >= 0; i-- {
+		if node_used[i] == slot_none {
+			continue
+		}
+		r.node.capacity++
+		if node_used[i] == slot_used {
+			continue
+		}
+		n = r.r2n(uint32(i))
+		*n = node{}
+		n.Left = r.node.next
+		r.node.next = uint32(i)
+		r.node.free++
+	}
+	r.leaf.next = null
+	r.leaf.free = 0
+	r.leaf.capacity = 0
+	for i = len(leaf_used) - 1; i >= 0; i-- {
+		if leaf_used[i] == slot_none {
+			continue
+		}
+		r.leaf.capacity++
+		if leaf_used[i] == slot_used {
+			continue
+		}
+		leaf = n2N[V](r.r2n(0x80000000 | uint32(i)))
+		*leaf = Leaf[V]{}
+		leaf.node.Left = r.leaf.next
+		r.leaf.next = 0x80000000 | uint32(i)
+		r.leaf.free++
+	}
+
+	r.rebuild_ranges()
+	r.node.reclaimed += reclaimed_node
+	r.leaf.reclaimed += reclaimed_leaf
+	/* The entries moved have lost their subtree count */
+	if r.counted {
+		r.count_build(r.Node)
+	}
+
+	return reclaimed_node + reclaimed_leaf
 }
@@ -88,3 +88,143 @@ func Test_ptr_range(t *testing.T) {
 		r.add_range(5, 10, 5, 0)
 	} ()
 }
+
+func TestCompact(t *testing.T) {
+	var r *Tree[uint32]
+	var keep map[uint32]bool
+	var leaves []*Leaf[uint32]
+	var n *Leaf[uint32]
+	var it *Iterator[uint32]
+	var key []byte
+	var reclaimed int
+	var count int
+	var i uint32
+
+	r = NewTree[uint32]()
+	keep = make(map[uint32]bool)
+	for i = 0; i < 300000; i++ {
+		key = []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+		n, _ = r.Insert(&key, 32, i)
+		leaves = append(leaves, n)
+	}
+	if len(r.leaf.pool) != 8 {
+		t.Fatalf("Expect 8 leaf chunks, got %d", len(r.leaf.pool))
+	}
+
+	/* Keep one entry every 50, spread in all chunks */
+	for i = 0; i < 300000; i++ {
+		if i % 50 == 0 {
+			keep[i] = true
+		} else {
+			r.Delete(leaves[i])
+		}
+	}
+	leaves = nil
+
+	reclaimed = r.Compact()
+	if reclaimed == 0 {
+		t.Fatalf("Expect reclaimed memory")
+	}
+	if len(r.leaf.pool) != 4 || len(r.node.pool) != 4 {
+		t.Errorf("Expect 4 leaf and node chunks, got %d and %d", len(r.leaf.pool), len(r.node.pool))
+	}
+	if r.Counters().Node.Reclaimed + r.Counters().Leaf.Reclaimed != reclaimed {
+		t.Errorf("Expect %d bytes reclaimed in counters", reclaimed)
+	}
+	if r.Counters().Leaf.Capacity - r.Counters().Leaf.Free != len(keep) {
+		t.Errorf("Expect %d leaves used, got %d", len(keep), r.Counters().Leaf.Capacity - r.Counters().Leaf.Free)
+	}
+	if r.Compact() != 0 {
+		t.Errorf("Expect nothing to reclaim")
+	}
+
+	/* Check content */
+	r.check_lvl1_and_die_on_error()
+	it = r.NewIter(nil, 0)
+	for it.Next() {
+		n = it.Get()
+		if !keep[n.Data] || (r.r2n(n.node.Parent).Left != r.n2r(&n.node) && r.r2n(n.node.Parent).Right != r.n2r(&n.node)) {
+			t.Fatalf("Unexpected leaf %d", n.Data)
+		}
+		count++
+	}
+	if count != len(keep) {
+		t.Fatalf("Expect %d leaves, got %d", len(keep), count)
+	}
+	for i = range keep {
+		key = []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+		n = r.Get(&key, 32)
+		if n == nil || n.Data != i {
+			t.Fatalf("Lookup of %d fails", i)
+		}
+	}
+
+	/* The tree is still usable */
+	for i = 300000; i < 400000; i++ {
+		key = []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+		r.Insert(&key, 32, i)
+	}
+	if r.Len() != len(keep) + 100000 {
+		t.Errorf("Expect %d leaves, got %d", len(keep) + 100000, r.Len())
+	}
+}
+
+func TestClone(t *testing.T) {
+	var r *Tree[[]int]
+	var c *Tree[[]int]
+	var n *Leaf[[]int]
+	var key []byte
+	var i int
+
+	r = NewTree[[]int]()
+	for i = 0; i < 1000; i++ {
+		key = []byte{10, byte(i >> 8), byte(i), 0}
+		r.Insert(&key, 24, []int{i})
+	}
+	key = []byte{10, 0, 5, 0}
+	r.Delete(r.Get(&key, 24))
+
+	/* Deep copy */
+	c = r.Clone(func(data []int)([]int) {
+		return append([]int{}, data...)
+	})
+	if c.Verify() != nil || c.Len() != r.Len() {
+		t.Fatalf("Bad clone: %v, %d leaves", c.Verify(), c.Len())
+	}
+	Diff(r, c, func(a []int, b []int)(bool) { return a[0] == b[0] }, func(kind DiffKind, o *Leaf[[]int], n *Leaf[[]int])(bool) {
+		t.Fatalf("Unexpected difference %d", kind)
+		return false
+	})
+	for n = range c.All() {
+		n.Data[0] = -1
+	}
+	for n = range r.All() {
+		if n.Data[0] == -1 {
+			t.Fatalf("Clone data shares the original data")
+		}
+	}
+
+	/* Modifications of the clone does not affect the original */
+	key = []byte{10, 0, 1, 0}
+	c.Delete(c.Get(&key, 24))
+	key = []byte{11, 0, 0, 0}
+	c.Insert(&key, 8, nil)
+	if r.Get(&key, 8) != nil || r.Len() != 999 || c.Len() != 999 {
+		t.Errorf("Original tree modified")
+	}
+	key = []byte{10, 0, 1, 0}
+	if r.Get(&key, 24) == nil {
+		t.Errorf("Original tree modified")
+	}
+	if r.Verify() != nil || c.Verify() != nil {
+		t.Errorf("Corrupted trees")
+	}
+
+	/* Shallow copy */
+	c = r.Clone(nil)
+	key = []byte{10, 0, 2, 0}
+	c.Get(&key, 24).Data[0] = -2
+	if r.Get(&key, 24).Data[0] != -2 {
+		t.Errorf("Expect shared data")
+	}
+}
@@ -19,8 +19,9 @@ func Test_ref(t *testing.T) {
 	r.node_growth()
 	r.node_growth()
 
-	if r.node.capacity != (5 * 65536) - 1 {
-		t.Fatalf("Expect capacity of %d, got %d", (5 * 65536) - 1, r.node.capacity)
+	/* chunks of 64, 256, 4096, 65536 and 65536 nodes */
+	if r.node.capacity != 64 + 256 + 4096 + (2 * 65536) - 1 {
+		t.Fatalf("Expect capacity of %d, got %d", 64 + 256 + 4096 + (2 * 65536) - 1, r.node.capacity)
 	}
 
 	ref = uint32(3 << 16 | 4343)
@@ -35,3 +36,45 @@ func Test_ref(t *testing.T) {
 		t.Fatalf("Expect reference %p, got %p", nref, nref_back)
 	}
 }
+
+func Test_chunk_size(t *testing.T) {
+	var r *Radix
+	var key []byte
+	var i int
+
+	r = NewRadix()
+	key = []byte{0, 0, 0, 0}
+	r.Insert(&key, 32, nil)
+	if len(r.leaf.pool[0].nodes) != 64 || len(r.node.pool) != 0 {
+		t.Fatalf("Expect first leaf chunk of 64 entries, got %d", len(r.leaf.pool[0].nodes))
+	}
+	for i = 1; i < 5000; i++ {
+		key = []byte{0, 0, byte(i >> 8), byte(i)}
+		r.Insert(&key, 32, nil)
+	}
+	if len(r.leaf.pool) != 4 || len(r.leaf.pool[3].nodes) != 65536 {
+		t.Fatalf("Expect 4 leaf chunks, got %d", len(r.leaf.pool))
+	}
+	if r.Counters().Leaf.Capacity != 64 + 256 + 4096 + 65536 {
+		t.Fatalf("Unexpected leaf capacity %d", r.Counters().Leaf.Capacity)
+	}
+	for i = 0; i < 5000; i++ {
+		key = []byte{0, 0, byte(i >> 8), byte(i)}
+		if r.Get(&key, 32) == nil {
+			t.Fatalf("Entry %d not found", i)
+		}
+	}
+
+	/* Capacity hint */
+	r = NewRadixCapacity(1000)
+	key = []byte{0, 0, 0, 0}
+	r.Insert(&key, 32, nil)
+	if len(r.leaf.pool[0].nodes) != 1000 {
+		t.Fatalf("Expect first leaf chunk of 1000 entries, got %d", len(r.leaf.pool[0].nodes))
+	}
+	r = NewRadixCapacity(1000000)
+	r.Insert(&key, 32, nil)
+	if len(r.leaf.pool[0].nodes) != 65536 {
+		t.Fatalf("Expect first leaf chunk of 65536 entries, got %d", len(r.leaf.pool[0].nodes))
+	}
+}
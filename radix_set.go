@@ -0,0 +1,122 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+/* Insert or replace prefix in the tree */
+func (r *Tree[V])set(key []byte, length int16, data V) {
+	var n *Leaf[V]
+
+	n, _ = r.Insert(&key, length, data)
+	n.Data = data
+}
+
+/* Insert in t the leaves of a, each leaf data is combined with the
+ * data of the longest covering leaf of b. If reverse is set, a and b
+ * are swapped in the combine call. If only is set, the leaves without
+ * covering leaf in b are ignored.
+ */
+func set_combine[V any](t *Tree[V], a *Tree[V], b *Tree[V], combine func(a V, b V)(V), reverse bool, only bool) {
+	var it *Iterator[V]
+	var n *Leaf[V]
+	var m *Leaf[V]
+	var key []byte
+	var length int16
+
+	it = a.NewIter(nil, 0)
+	for it.Next() {
+		n = it.Get()
+		key = []byte(n.node.Bytes)
+		length = n.node.End + 1
+		m = b.LookupLonguest(&key, length)
+		switch {
+		case m == nil && only:
+			continue
+		case m == nil:
+			t.set(key, length, n.Data)
+		case reverse:
+			t.set(key, length, combine(m.Data, n.Data))
+		default:
+			t.set(key, length, combine(n.Data, m.Data))
+		}
+	}
+}
+
+// Union return a new tree which covers the address space covered by a or
+// by b. The data of a prefix covered by both trees is the result of
+// combine with the data of the longest covering prefix of a and b. If
+// combine is nil, the data of a is used.
+func Union[V any](a *Tree[V], b *Tree[V], combine func(a V, b V)(V))(*Tree[V]) {
+	var t *Tree[V]
+
+	if combine == nil {
+		combine = func(a V, b V)(V) { return a }
+	}
+	t = NewTree[V]()
+	set_combine(t, a, b, combine, false, false)
+	set_combine(t, b, a, combine, true, false)
+	return t
+}
+
+// Intersect return a new tree which covers the address space covered by
+// both a and b. The data of each prefix is the result of combine with the
+// data of the longest covering prefix of a and b. If combine is nil, the
+// data of a is used.
+func Intersect[V any](a *Tree[V], b *Tree[V], combine func(a V, b V)(V))(*Tree[V]) {
+	var t *Tree[V]
+
+	if combine == nil {
+		combine = func(a V, b V)(V) { return a }
+	}
+	t = NewTree[V]()
+	set_combine(t, a, b, combine, false, true)
+	set_combine(t, b, a, combine, true, true)
+	return t
+}
+
+/* Insert in t the parts of the prefix not covered by b. The prefix
+ * is split in two halves while it contains prefixes of b.
+ */
+func (r *Tree[V])subtract(b *Tree[V], key []byte, length int16, data V) {
+	var right []byte
+
+	if b.LookupLonguest(&key, length) != nil {
+		return
+	}
+	if !b.NewIter(&key, length).Next() {
+		r.set(key, length, data)
+		return
+	}
+
+	/* Split the prefix */
+	if int(length) >= len(key) * 8 {
+		key = append(key, 0)
+	}
+	right = append([]byte{}, key...)
+	key[length / 8] &^= 0x80 >> (length % 8)
+	right[length / 8] |= 0x80 >> (length % 8)
+	r.subtract(b, key, length + 1, data)
+	r.subtract(b, right, length + 1, data)
+}
+
+// Subtract return a new tree which covers the address space covered by a
+// and not covered by b. The prefixes of a partially covered by b are split,
+// so 10.0.0.0/8 minus 10.1.0.0/16 gives the complementary prefixes
+// 10.0.0.0/16, 10.2.0.0/15, 10.4.0.0/14 ... 10.128.0.0/9. The data are the
+// data of a.
+func Subtract[V any](a *Tree[V], b *Tree[V])(*Tree[V]) {
+	var t *Tree[V]
+	var it *Iterator[V]
+	var n *Leaf[V]
+
+	/* The tree order ensure a prefix is processed before its more
+	 * specific prefixes, so their data replaces the data of the
+	 * covering prefix parts.
+	 */
+	t = NewTree[V]()
+	it = a.NewIter(nil, 0)
+	for it.Next() {
+		n = it.Get()
+		t.subtract(b, []byte(n.node.Bytes), n.node.End + 1, n.Data)
+	}
+	return t
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "math/rand"
+import "net/netip"
+import "testing"
+
+func set_random_tree()(*Tree[int]) {
+	var r *Tree[int]
+	var i int
+
+	r = NewTree[int]()
+	for i = 0; i < 300; i++ {
+		r.PrefixInsert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(rand.Intn(4)), byte(rand.Intn(256)), 0}), 14 + rand.Intn(11)), rand.Intn(100))
+	}
+	return r
+}
+
+func TestSubtract(t *testing.T) {
+	var a *Tree[string]
+	var b *Tree[string]
+	var s *Tree[string]
+	var got []string
+	var expect []string
+	var i int
+
+	a = NewTree[string]()
+	b = NewTree[string]()
+	a.PrefixInsert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	b.PrefixInsert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	s = Subtract(a, b)
+	got = aggregate_dump(s)
+	expect = []string{
+		"10.0.0.0/16=a", "10.128.0.0/9=a", "10.16.0.0/12=a", "10.2.0.0/15=a",
+		"10.32.0.0/11=a", "10.4.0.0/14=a", "10.64.0.0/10=a", "10.8.0.0/13=a",
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("Expect %v, got %v", expect, got)
+	}
+	for i = range got {
+		if got[i] != expect[i] {
+			t.Fatalf("Expect %v, got %v", expect, got)
+		}
+	}
+}
+
+func TestSetRandom(t *testing.T) {
+	var a *Tree[int]
+	var b *Tree[int]
+	var u *Tree[int]
+	var in *Tree[int]
+	var s *Tree[int]
+	var la *Leaf[int]
+	var lb *Leaf[int]
+	var l *Leaf[int]
+	var addr netip.Addr
+	var sum func(x int, y int)(int)
+	var i int
+
+	sum = func(x int, y int)(int) { return x * 1000 + y }
+	a = set_random_tree()
+	b = set_random_tree()
+	u = Union(a, b, sum)
+	in = Intersect(a, b, sum)
+	s = Subtract(a, b)
+
+	for i = 0; i < 50000; i++ {
+		addr = netip.AddrFrom4([4]byte{10, byte(rand.Intn(5)), byte(rand.Intn(256)), byte(rand.Intn(256))})
+		la = a.AddrLookupLonguest(addr)
+		lb = b.AddrLookupLonguest(addr)
+
+		/* Union */
+		l = u.AddrLookupLonguest(addr)
+		switch {
+		case la == nil && lb == nil:
+			if l != nil {
+				t.Fatalf("Union: unexpected match for %s", addr)
+			}
+		case la == nil:
+			if l == nil || l.Data != lb.Data {
+				t.Fatalf("Union: bad match for %s", addr)
+			}
+		case lb == nil:
+			if l == nil || l.Data != la.Data {
+				t.Fatalf("Union: bad match for %s", addr)
+			}
+		default:
+			if l == nil || l.Data != sum(la.Data, lb.Data) {
+				t.Fatalf("Union: bad match for %s", addr)
+			}
+		}
+
+		/* Intersect */
+		l = in.AddrLookupLonguest(addr)
+		if la == nil || lb == nil {
+			if l != nil {
+				t.Fatalf("Intersect: unexpected match for %s", addr)
+			}
+		} else if l == nil || l.Data != sum(la.Data, lb.Data) {
+			t.Fatalf("Intersect: bad match for %s", addr)
+		}
+
+		/* Subtract */
+		l = s.AddrLookupLonguest(addr)
+		if la == nil || lb != nil {
+			if l != nil {
+				t.Fatalf("Subtract: unexpected match for %s", addr)
+			}
+		} else if l == nil || l.Data != la.Data {
+			t.Fatalf("Subtract: bad match for %s", addr)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "sync"
+import "sync/atomic"
+import "unsafe"
+
+// Snapshot is an immutable version of a tree published by a SnapshotTree.
+// A Snapshot could be queried without lock by many goroutines. It must be
+// released with Release when the reader no longer use it, so its memory
+// could be reused by the writer.
+type Snapshot[V any] struct {
+	tree *Tree[V]
+	readers atomic.Int32
+}
+
+// Tree return the tree of the snapshot. The tree must not be modified,
+// only the lookup and browsing functions could be used. The tree and
+// its leaves must not be used after the Release of the snapshot.
+func (s *Snapshot[V])Tree()(*Tree[V]) {
+	return s.tree
+}
+
+// Release signal the reader no longer use the snapshot.
+func (s *Snapshot[V])Release() {
+	s.readers.Add(-1)
+}
+
+// SnapshotTree is a tree updated by copy-on-write. The readers take the
+// current Snapshot and query it without lock while a writer builds the
+// next version. The next version is published atomically when the writer
+// ends.
+//
+// The versions share the node and leaf chunks. An update copies only the
+// chunks containing the nodes it modifies, so its cost depends on the
+// number of modifications and not on the tree size. The chunks copied by
+// the previous version are reused by the next update if no reader holds
+// the previous version anymore.
+type SnapshotTree[V any] struct {
+	lock sync.Mutex
+	current atomic.Pointer[Snapshot[V]]
+	retired *Snapshot[V]
+	gen uint64
+}
+
+// SnapshotRadix is the untyped tree updated by copy-on-write.
+type SnapshotRadix = SnapshotTree[any]
+
+/* Copy-on-write state of a tree built by a SnapshotTree update. The
+ * chunks created by the update have the generation gen, the others are
+ * shared with the published versions and must be copied before any
+ * modification. The spare chunks are indexed like the pools and are
+ * reused for the copies.
+ */
+type cow_state[V any] struct {
+	gen uint64
+	node []*node_chunk
+	leaf []*leaf_chunk[V]
+}
+
+// NewSnapshotRadix return initialized *SnapshotRadix tree.
+func NewSnapshotRadix()(*SnapshotRadix) {
+	return NewSnapshotTree[any]()
+}
+
+// NewSnapshotTree return initialized *SnapshotTree tree.
+func NewSnapshotTree[V any]()(*SnapshotTree[V]) {
+	var s *SnapshotTree[V]
+
+	s = &SnapshotTree[V]{}
+	s.current.Store(&Snapshot[V]{tree: NewTree[V]()})
+	return s
+}
+
+/* Make the node chunk index private to the tree. A shared chunk is
+ * copied in a spare chunk or in a new chunk. The old chunk range is kept,
+ * so the nodes obtained before the copy keep their reference.
+ */
+func (r *Tree[V])cow_node(index int) {
+	var cn *node_chunk
+
+	if r.node.pool[index].gen == r.cow.gen {
+		return
+	}
+	if index < len(r.cow.node) && r.cow.node[index] != nil {
+		cn = r.cow.node[index]
+		r.cow.node[index] = nil
+	} else {
+		cn = &node_chunk{nodes: make([]node, len(r.node.pool[index].nodes))}
+		cn.ptr = (uintptr)(unsafe.Pointer(&cn.nodes[0]))
+	}
+	copy(cn.nodes, r.node.pool[index].nodes)
+	cn.count = copy_count(cn.count, r.node.pool[index].count)
+	cn.gen = r.cow.gen
+	r.node.pool[index] = cn
+	r.add_range(cn.ptr, (uintptr)(unsafe.Pointer(&cn.nodes[len(cn.nodes) - 1])), index, kind_node)
+}
+
+/* Make the leaf chunk index private to the tree, like cow_node */
+func (r *Tree[V])cow_leaf(index int) {
+	var cl *leaf_chunk[V]
+
+	if r.leaf.pool[index].gen == r.cow.gen {
+		return
+	}
+	if index < len(r.cow.leaf) && r.cow.leaf[index] != nil {
+		cl = r.cow.leaf[index]
+		r.cow.leaf[index] = nil
+	} else {
+		cl = &leaf_chunk[V]{nodes: make([]Leaf[V], len(r.leaf.pool[index].nodes))}
+		cl.ptr = (uintptr)(unsafe.Pointer(&cl.nodes[0]))
+	}
+	copy(cl.nodes, r.leaf.pool[index].nodes)
+	cl.count = copy_count(cl.count, r.leaf.pool[index].count)
+	cl.gen = r.cow.gen
+	r.leaf.pool[index] = cl
+	r.add_range(cl.ptr, (uintptr)(unsafe.Pointer(&cl.nodes[len(cl.nodes) - 1])), index, kind_leaf)
+}
+
+/* Make the chunk containing ref private to the tree */
+func (r *Tree[V])cow_ref(ref uint32) {
+	if ref == null {
+		return
+	}
+	if is_leaf(ref) {
+		r.cow_leaf(int((ref >> 16) & 0x7fff))
+	} else {
+		r.cow_node(int(ref >> 16))
+	}
+}
+
+/* Make private the chunks of ref and of its parents up to the root */
+func (r *Tree[V])cow_path(ref uint32) {
+	for ref != null {
+		r.cow_ref(ref)
+		ref = r.r2n(ref).Parent
+	}
+}
+
+/* Make private the chunks modified by the insertion of key/length: the
+ * lookup path, the children of the last node and the next free slots.
+ */
+func (r *Tree[V])cow_insert(key []byte, length int16) {
+	var n *node
+	var ref uint32
+
+	n, ref = lookup_longuest_last_node(r, key, length)
+	if n != nil {
+		r.cow_path(ref)
+		n = r.r2n(ref)
+		r.cow_ref(n.Left)
+		r.cow_ref(n.Right)
+	}
+	r.cow_ref(r.node.next)
+	r.cow_ref(r.leaf.next)
+}
+
+/* Make private the chunks modified by the deletion of ref: its path up
+ * to the root, its children, its sibling and the next free node.
+ */
+func (r *Tree[V])cow_delete(ref uint32) {
+	var n *node
+	var p *node
+
+	r.cow_path(ref)
+	n = r.r2n(ref)
+	r.cow_ref(n.Left)
+	r.cow_ref(n.Right)
+	if n.Parent != null {
+		p = r.r2n(n.Parent)
+		r.cow_ref(p.Left)
+		r.cow_ref(p.Right)
+	}
+	r.cow_ref(r.node.next)
+}
+
+/* Make private all the chunks */
+func (r *Tree[V])cow_all() {
+	var i int
+
+	for i = range r.node.pool {
+		r.cow_node(i)
+	}
+	for i = range r.leaf.pool {
+		r.cow_leaf(i)
+	}
+}
+
+/* Return the current version of the node n. During an update, n could
+ * be a node of a chunk copied after n was obtained.
+ */
+func (r *Tree[V])fresh(n *node)(*node) {
+	if n == nil || r.cow == nil {
+		return n
+	}
+	return r.r2n(r.n2r(n))
+}
+
+/* Prepare r as the next version of src. The chunks are shared, the
+ * chunks created by the retired version and no longer used by src are
+ * kept as spare chunks.
+ */
+func (r *Tree[V])share_from(src *Tree[V], gen uint64, retired *Tree[V], retired_gen uint64) {
+	var i int
+
+	*r = *src
+	r.node.pool = append([]*node_chunk(nil), src.node.pool...)
+	r.leaf.pool = append([]*leaf_chunk[V](nil), src.leaf.pool...)
+	r.ptr_range = nil
+	r.rebuild_ranges()
+	r.cow = &cow_state[V]{gen: gen}
+
+	if retired == nil {
+		return
+	}
+	r.cow.node = make([]*node_chunk, len(retired.node.pool))
+	for i = range retired.node.pool {
+		if retired.node.pool[i].gen == retired_gen && (i >= len(src.node.pool) || src.node.pool[i] != retired.node.pool[i]) {
+			r.cow.node[i] = retired.node.pool[i]
+		}
+	}
+	r.cow.leaf = make([]*leaf_chunk[V], len(retired.leaf.pool))
+	for i = range retired.leaf.pool {
+		if retired.leaf.pool[i].gen == retired_gen && (i >= len(src.leaf.pool) || src.leaf.pool[i] != retired.leaf.pool[i]) {
+			r.cow.leaf[i] = retired.leaf.pool[i]
+		}
+	}
+}
+
+// Snapshot return the current version of the tree. This function never
+// lock. The snapshot must be released with Release.
+func (s *SnapshotTree[V])Snapshot()(*Snapshot[V]) {
+	var snap *Snapshot[V]
+
+	for {
+		snap = s.current.Load()
+		snap.readers.Add(1)
+
+		/* If the snapshot is no longer the current, the writer
+		 * may reuse it, so try again.
+		 */
+		if s.current.Load() == snap {
+			return snap
+		}
+		snap.Release()
+	}
+}
+
+// View execute fn with the current version of the tree. The tree must
+// not be modified and its leaves must not be used after fn return.
+func (s *SnapshotTree[V])View(fn func(t *Tree[V])) {
+	var snap *Snapshot[V]
+
+	snap = s.Snapshot()
+	defer snap.Release()
+	fn(snap.tree)
+}
+
+// Update build the next version of the tree. fn receives the next
+// version which shares its chunks with the current version and could be
+// modified. When fn returns, it is atomically published as the current
+// version. Updates are serialized.
+//
+// The leaves given by the lookup and browsing functions could be stored
+// in a chunk shared with the published versions, so their Data must not
+// be modified: the leaf returned by Insert for an existing prefix could
+// be modified. Compact, EnableCounts and DisableCounts copy all the
+// chunks.
+func (s *SnapshotTree[V])Update(fn func(t *Tree[V])) {
+	var cur *Snapshot[V]
+	var next *Snapshot[V]
+	var retired *Tree[V]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	/* Reuse the chunks of the retired version if no reader holds it.
+	 * A reader could still increment its counter, but it check the
+	 * snapshot is current before using it, and it is not current.
+	 */
+	cur = s.current.Load()
+	if s.retired != nil && s.retired.readers.Load() == 0 {
+		retired = s.retired.tree
+	}
+	next = &Snapshot[V]{tree: &Tree[V]{}}
+	next.tree.share_from(cur.tree, s.gen + 1, retired, s.gen - 1)
+	s.gen++
+	fn(next.tree)
+
+	/* The published version is read only, the ranges of the copied
+	 * chunks are no longer used.
+	 */
+	next.tree.cow = nil
+	next.tree.rebuild_ranges()
+	s.current.Store(next)
+	s.retired = cur
+}
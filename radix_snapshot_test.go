@@ -0,0 +1,284 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "encoding/binary"
+import "math/rand"
+import "net"
+import "sync"
+import "testing"
+
+func TestSnapshotTree(t *testing.T) {
+	var s *SnapshotTree[int]
+	var snap *Snapshot[int]
+	var old *Snapshot[int]
+	var wg sync.WaitGroup
+	var stop chan struct{}
+	var key []byte
+	var i int
+
+	s = NewSnapshotTree[int]()
+
+	/* Readers always see a complete version: each update insert
+	 * 100 entries, and the data is the version number.
+	 */
+	stop = make(chan struct{})
+	for i = 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			var k []byte
+			var n *Leaf[int]
+			var sn *Snapshot[int]
+			var count int
+
+			defer wg.Done()
+			k = make([]byte, 4)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				sn = s.Snapshot()
+				if sn.Tree().Len() % 100 != 0 {
+					t.Errorf("Expect complete version, got %d entries", sn.Tree().Len())
+				}
+				count = 0
+				for n = sn.Tree().First(); n != nil; n = sn.Tree().Next(n) {
+					count++
+				}
+				if count != sn.Tree().Len() {
+					t.Errorf("Expect %d entries, got %d", sn.Tree().Len(), count)
+				}
+				binary.BigEndian.PutUint32(k, 0)
+				sn.Tree().LookupLonguest(&k, 32)
+				sn.Release()
+			}
+		}()
+	}
+	for i = 0; i < 50; i++ {
+		s.Update(func(tr *Tree[int]) {
+			var k []byte
+			var j int
+
+			k = make([]byte, 4)
+			for j = 0; j < 100; j++ {
+				binary.BigEndian.PutUint32(k, uint32(i * 100 + j))
+				tr.Insert(&k, 32, i)
+			}
+		})
+	}
+	close(stop)
+	wg.Wait()
+
+	/* A snapshot is not modified by the next updates */
+	snap = s.Snapshot()
+	s.Update(func(tr *Tree[int]) {
+		tr.Delete(tr.First())
+	})
+	old = s.Snapshot()
+	if snap.Tree().Len() != 5000 || old.Tree().Len() != 4999 {
+		t.Errorf("Expect snapshot unmodified")
+	}
+	old.Release()
+	key = make([]byte, 4)
+	if snap.Tree().Get(&key, 32) == nil {
+		t.Errorf("Expect first entry in the snapshot")
+	}
+	snap.Release()
+
+}
+
+func TestSnapshotShare(t *testing.T) {
+	var s *SnapshotTree[int]
+	var a *Tree[int]
+	var b *Tree[int]
+	var c *Tree[int]
+	var spare *leaf_chunk[int]
+	var copied int
+	var i int
+
+	s = NewSnapshotTree[int]()
+	s.Update(func(tr *Tree[int]) {
+		var k []byte
+		var j int
+
+		k = make([]byte, 4)
+		for j = 0; j < 200000; j++ {
+			binary.BigEndian.PutUint32(k, uint32(j) * 7919)
+			tr.Insert(&k, 32, j)
+		}
+	})
+	a = s.current.Load().tree
+
+	/* One insert copies only the chunks it modifies */
+	s.Update(func(tr *Tree[int]) {
+		var k []byte
+
+		k = []byte{0, 0, 0x30, 0x39}
+		tr.Insert(&k, 32, -1)
+	})
+	b = s.current.Load().tree
+	for i = range a.node.pool {
+		if a.node.pool[i] != b.node.pool[i] {
+			copied++
+		}
+	}
+	for i = range a.leaf.pool {
+		if a.leaf.pool[i] != b.leaf.pool[i] {
+			copied++
+			spare = a.leaf.pool[i]
+		}
+	}
+	if copied == 0 || copied >= len(a.node.pool) + len(a.leaf.pool) {
+		t.Errorf("Expect shared chunks, got %d copied chunks on %d", copied, len(a.node.pool) + len(a.leaf.pool))
+	}
+	if a.Len() != 200000 || b.Len() != 200001 || a.Verify() != nil || b.Verify() != nil {
+		t.Fatalf("Expect valid versions")
+	}
+
+	/* The chunks copied by the retired version are reused when no
+	 * reader holds it.
+	 */
+	s.Update(func(tr *Tree[int]) {
+		var k []byte
+
+		k = []byte{0, 0, 0x30, 0x39}
+		tr.Delete(tr.Get(&k, 32))
+	})
+	c = s.current.Load().tree
+	for i = range c.leaf.pool {
+		if c.leaf.pool[i] == spare {
+			spare = nil
+		}
+	}
+	if spare != nil {
+		t.Errorf("Expect retired chunk reused")
+	}
+	if b.Len() != 200001 || b.Verify() != nil || c.Len() != 200000 || c.Verify() != nil {
+		t.Fatalf("Expect valid versions")
+	}
+}
+
+func TestSnapshotUpdate(t *testing.T) {
+	var s *SnapshotTree[int]
+	var snaps []*Snapshot[int]
+	var models []map[uint32]int
+	var model map[uint32]int
+	var rnd *rand.Rand
+	var snap *Snapshot[int]
+	var n *Leaf[int]
+	var key []byte
+	var v uint32
+	var i int
+	var j int
+
+	rnd = rand.New(rand.NewSource(5))
+	s = NewSnapshotTree[int]()
+	model = make(map[uint32]int)
+	for i = 0; i < 200; i++ {
+		s.Update(func(tr *Tree[int]) {
+			var k []byte
+			var l *Leaf[int]
+			var m *Leaf[int]
+			var ok bool
+			var j int
+
+			k = make([]byte, 4)
+			for j = 0; j < 50; j++ {
+				v = uint32(rnd.Intn(2000)) << 20
+				binary.BigEndian.PutUint32(k, v)
+				if rnd.Intn(3) == 0 {
+					l = tr.Get(&k, 12)
+					if l != nil {
+						tr.Delete(l)
+						delete(model, v)
+					}
+					continue
+				}
+				l, ok = tr.Insert(&k, 12, i)
+				if ok || rnd.Intn(2) == 0 {
+					l.Data = i
+					model[v] = i
+				}
+			}
+
+			/* A leaf obtained before a modification stays usable */
+			l = tr.First()
+			binary.BigEndian.PutUint32(k, 0xfff00000)
+			m, _ = tr.Insert(&k, 12, i)
+			model[0xfff00000] = m.Data
+			if l != nil && tr.Next(l) == nil {
+				t.Errorf("Expect next leaf")
+			}
+			if l != nil {
+				delete(model, binary.BigEndian.Uint32([]byte(l.node.Bytes)))
+				tr.Delete(l)
+			}
+		})
+
+		/* Keep some versions and check they are never modified */
+		snaps = append(snaps, s.Snapshot())
+		models = append(models, make(map[uint32]int))
+		for v, j = range model {
+			models[len(models) - 1][v] = j
+		}
+		if len(snaps) > 5 {
+			snaps[0].Release()
+			snaps = snaps[1:]
+			models = models[1:]
+		}
+		for j, snap = range snaps {
+			if snap.Tree().Verify() != nil || snap.Tree().Len() != len(models[j]) {
+				t.Fatalf("Version %d modified", i - len(snaps) + j + 1)
+			}
+			for n = range snap.Tree().All() {
+				key = []byte(n.node.Bytes)
+				if models[j][binary.BigEndian.Uint32(key)] != n.Data {
+					t.Fatalf("Version %d modified", i - len(snaps) + j + 1)
+				}
+			}
+		}
+	}
+}
+
+func TestSnapshotStaleLeaf(t *testing.T) {
+	var s *SnapshotTree[int]
+	var nw *net.IPNet
+
+	s = NewSnapshotTree[int]()
+	s.Update(func(tr *Tree[int]) {
+		_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+		tr.IPv4Insert(nw, 10)
+		_, nw, _ = net.ParseCIDR("11.0.0.0/8")
+		tr.IPv4Insert(nw, 11)
+	})
+
+	/* The leaf is obtained before its chunk is copied by the insert
+	 * of its child.
+	 */
+	s.Update(func(tr *Tree[int]) {
+		var l *Leaf[int]
+		var n *Leaf[int]
+		var it *Iterator[int]
+
+		_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+		l = tr.IPv4Get(nw)
+		it = tr.NewIter(nil, 0)
+		_, nw, _ = net.ParseCIDR("10.1.0.0/16")
+		tr.IPv4Insert(nw, 101)
+		n = tr.Next(l)
+		if n == nil || n.Data != 101 {
+			t.Errorf("Expect next leaf 10.1.0.0/16")
+		}
+		if tr.Rank(l) != 0 || tr.Rank(n) != 1 {
+			t.Errorf("Unexpected leaf order")
+		}
+		if !it.Next() || it.Get().Data != 10 || !it.Next() || it.Get().Data != 101 {
+			t.Errorf("Expect iteration on the current version")
+		}
+	})
+	if s.current.Load().tree.Len() != 3 || s.current.Load().tree.Verify() != nil {
+		t.Errorf("Expect valid version")
+	}
+}
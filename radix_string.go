@@ -2,6 +2,8 @@
 
 package radix
 
+import "iter"
+
 func string_to_key(str string)([]byte, int16) {
 	if len(str) * 8 > 32767 {
 		return nil, 0
@@ -11,7 +13,7 @@ func string_to_key(str string)([]byte, int16) {
 
 // StringLookupLonguest get a string as prefix and return the leaf which match the
 // longest part of the prefix. Return nil if none match.
-func (r *Radix)StringLookupLonguest(str string)(*Node) {
+func (r *Tree[V])StringLookupLonguest(str string)(*Leaf[V]) {
 	var length int16
 	var key []byte
 
@@ -25,16 +27,32 @@ func (r *Radix)StringLookupLonguest(str string)(*Node) {
 	return r.LookupLonguest(&key, length)
 }
 
+// StringLookupShortest get a string as prefix and return the leaf which match the
+// shortest part of the prefix. Return nil if none match.
+func (r *Tree[V])StringLookupShortest(str string)(*Leaf[V]) {
+	var length int16
+	var key []byte
+
+	/* Get the network width. width of 0 id prohibited */
+	key, length = string_to_key(str)
+	if length == 0 {
+		return nil
+	}
+
+	/* Perform lookup */
+	return r.LookupShortest(&key, length)
+}
+
 // StringLookupLonguestPath take the radix tree and a string as prefix, return the list
 // of all leaf matching the prefix. If none match, return nil
-func (r *Radix)StringLookupLonguestPath(str string)([]*Node) {
+func (r *Tree[V])StringLookupLonguestPath(str string)([]*Leaf[V]) {
 	var length int16
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
 	key, length = string_to_key(str)
 	if length == 0 {
-		return make([]*Node, 0)
+		return make([]*Leaf[V], 0)
 	}
 
 	/* Perform lookup */
@@ -43,7 +61,7 @@ func (r *Radix)StringLookupLonguestPath(str string)([]*Node) {
 
 // Get gets a string as prefix and return exact match of the prefix. Exact match
 // is a node wich match the prefix bit and the length.
-func (r *Radix)StringGet(str string)(*Node) {
+func (r *Tree[V])StringGet(str string)(*Leaf[V]) {
 	var length int16
 	var key []byte
 
@@ -60,7 +78,7 @@ func (r *Radix)StringGet(str string)(*Node) {
 // StringInsert string as prefix in the tree. The tree accept only unique value, if
 // the prefix already exists in the tree, return existing leaf,
 // otherwaise return nil.
-func (r *Radix)StringInsert(str string, data interface{})(*Node, bool) {
+func (r *Tree[V])StringInsert(str string, data V)(*Leaf[V], bool) {
 	var length int16
 	var key []byte
 
@@ -76,8 +94,8 @@ func (r *Radix)StringInsert(str string, data interface{})(*Node, bool) {
 
 // StringDelete lookup string and remove it. does nothing
 // if the string not exists.
-func (r *Radix)StringDelete(str string)() {
-	var node *Node
+func (r *Tree[V])StringDelete(str string)() {
+	var node *Leaf[V]
 	var length int16
 	var key []byte
 
@@ -99,7 +117,7 @@ func (r *Radix)StringDelete(str string)() {
 
 // StringNewIter return struct Iter for browsing all nodes there children
 // match the string prefix.
-func (r *Radix)StringNewIter(str string)(*Iter) {
+func (r *Tree[V])StringNewIter(str string)(*Iterator[V]) {
 	var length int16
 	var key []byte
 
@@ -108,6 +126,67 @@ func (r *Radix)StringNewIter(str string)(*Iter) {
 }
 
 // StringGetKey convert node key/length prefix to string
-func (n *Node)StringGetKey()(string) {
+func (n *Leaf[V])StringGetKey()(string) {
 	return string(n.node.Bytes)
 }
+
+// StringPrefixed return an iterator on the string key and the data of
+// all the leaves starting with str, in tree order.
+func (r *Tree[V])StringPrefixed(str string)(iter.Seq2[string, V]) {
+	return func(yield func(string, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = string_to_key(str)
+		if key == nil {
+			return
+		}
+		for n = range r.Prefixed(&key, length) {
+			if !yield(n.StringGetKey(), n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// StringCovering return an iterator on the string key and the data of
+// the leaves which are a prefix of str, from the shortest to the longest.
+func (r *Tree[V])StringCovering(str string)(iter.Seq2[string, V]) {
+	return func(yield func(string, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = string_to_key(str)
+		if length == 0 {
+			return
+		}
+		for n = range r.Covering(&key, length) {
+			if !yield(n.StringGetKey(), n.Data) {
+				return
+			}
+		}
+	}
+}
+
+// StringCoveringBackward return an iterator on the string key and the
+// data of the leaves which are a prefix of str, from the longest to the
+// shortest.
+func (r *Tree[V])StringCoveringBackward(str string)(iter.Seq2[string, V]) {
+	return func(yield func(string, V)(bool)) {
+		var length int16
+		var key []byte
+		var n *Leaf[V]
+
+		key, length = string_to_key(str)
+		if length == 0 {
+			return
+		}
+		for n = range r.CoveringBackward(&key, length) {
+			if !yield(n.StringGetKey(), n.Data) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,550 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "io"
+import "net"
+import "net/netip"
+import "sync"
+import "time"
+
+// SyncTree is a tree protected by a reader/writer lock. Lookup functions
+// take the read lock, modification functions take the write lock.
+//
+// The leaves returned by SyncTree are copies taken under the lock, so
+// they are never modified or released by a concurrent Delete. A copy is
+// detached from the tree: modifying its Data does not modify the tree
+// and it must not be given to the underlying Tree functions. The
+// SyncTree functions Next, Prev and Delete accept these copies because
+// they lookup the leaf by its key.
+type SyncTree[V any] struct {
+	lock sync.RWMutex
+	tree *Tree[V]
+}
+
+// SyncRadix is the untyped tree protected by a reader/writer lock.
+type SyncRadix = SyncTree[any]
+
+// NewSyncRadix return initialized *SyncRadix tree.
+func NewSyncRadix()(*SyncRadix) {
+	return NewSyncTree[any]()
+}
+
+// NewSyncTree return initialized *SyncTree tree.
+func NewSyncTree[V any]()(*SyncTree[V]) {
+	return &SyncTree[V]{
+		tree: NewTree[V](),
+	}
+}
+
+/* Return a copy of the leaf which could be used after the lock
+ * release.
+ */
+func leaf_copy[V any](n *Leaf[V])(*Leaf[V]) {
+	var c *Leaf[V]
+
+	if n == nil {
+		return nil
+	}
+	c = &Leaf[V]{}
+	*c = *n
+	return c
+}
+
+func leaf_copies[V any](ns []*Leaf[V])([]*Leaf[V]) {
+	var i int
+
+	for i = range ns {
+		ns[i] = leaf_copy(ns[i])
+	}
+	return ns
+}
+
+/* Return the leaf of the tree matching the key of n. n could be
+ * a copy of the leaf. The lock must be held.
+ */
+func (s *SyncTree[V])lookup(n *Leaf[V])(*Leaf[V]) {
+	var key []byte
+
+	key = []byte(n.node.Bytes)
+	return s.tree.Get(&key, n.node.End + 1)
+}
+
+/* Browse all leaves matching prefix. The read lock must be held. */
+func (s *SyncTree[V])browse(it *Iterator[V], fn func(n *Leaf[V])(bool)) {
+	for it.Next() {
+		if !fn(it.Get()) {
+			return
+		}
+	}
+}
+
+// View execute fn with the read lock held. The tree given to fn must
+// not be modified and the leaves must not be used after fn return.
+func (s *SyncTree[V])View(fn func(t *Tree[V])) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	fn(s.tree)
+}
+
+// Update execute fn with the write lock held. The leaves must not be
+// used after fn return.
+func (s *SyncTree[V])Update(fn func(t *Tree[V])) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn(s.tree)
+}
+
+// Range browse all leaves there children match the given key/length
+// prefix and call fn for each leaf with the read lock held. The browsing
+// stops if fn return false. The leaf given to fn is the leaf of the
+// tree, it must not be used after fn return. fn must not call any
+// SyncTree function, even a lookup: taking the read lock again could
+// deadlock if a writer is waiting for the lock. Use View for nested
+// reads, its fn receives the underlying tree.
+func (s *SyncTree[V])Range(key *[]byte, length int16, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.NewIter(key, length), fn)
+}
+
+// Len return the number of leaf in the tree.
+func (s *SyncTree[V])Len()(int) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.tree.Len()
+}
+
+// Counters return counters useful to monitor the radix tree
+// usage.
+func (s *SyncTree[V])Counters()(*Counters) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.tree.Counters()
+}
+
+// Debug display the tree on fh.
+func (s *SyncTree[V])Debug(fh io.Writer) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.tree.Debug(fh)
+}
+
+// LookupLonguestPath take the radix tree and a key/length prefix, return the list
+// of copies of all leaf matching the prefix. If none match, return nil
+func (s *SyncTree[V])LookupLonguestPath(data *[]byte, length int16)([]*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copies(s.tree.LookupLonguestPath(data, length))
+}
+
+// LookupLonguest get a key/length prefix and return a copy of the leaf which
+// match the longest part of the prefix. Return nil if none match.
+func (s *SyncTree[V])LookupLonguest(data *[]byte, length int16)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.LookupLonguest(data, length))
+}
+
+// LookupGe return a copy of the greater or equal closest leaf of the key.
+// See Tree.LookupGe.
+func (s *SyncTree[V])LookupGe(data *[]byte, length int16)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.LookupGe(data, length))
+}
+
+// LookupLe return a copy of the lesser or equal closest leaf of the key.
+// See Tree.LookupLe.
+func (s *SyncTree[V])LookupLe(data *[]byte, length int16)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.LookupLe(data, length))
+}
+
+// Get gets a key/length prefix and return a copy of the exact match of the
+// prefix.
+func (s *SyncTree[V])Get(data *[]byte, length int16)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.Get(data, length))
+}
+
+// Insert key/length prefix in the tree. Return a copy of the leaf and true
+// if the leaf is inserted, or a copy of the existing leaf and false.
+func (s *SyncTree[V])Insert(key *[]byte, length int16, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.Insert(key, length, data)
+	return leaf_copy(n), ok
+}
+
+// Delete remove the leaf which have the same key/length prefix than n.
+// n could be a leaf copy. Does nothing if the leaf not exists.
+func (s *SyncTree[V])Delete(n *Leaf[V]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n = s.lookup(n)
+	if n == nil {
+		return
+	}
+	s.tree.Delete(n)
+}
+
+// First return a copy of the first leaf of the tree. Return nil if the tree
+// is empty.
+func (s *SyncTree[V])First()(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.First())
+}
+
+// Last return a copy of the last leaf of the tree. Return nil if the tree
+// is empty.
+func (s *SyncTree[V])Last()(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.Last())
+}
+
+// Next return a copy of the next leaf in browsing order. n could be a leaf
+// copy. Return nil if we reach end of tree or if n is no longer in the tree.
+func (s *SyncTree[V])Next(n *Leaf[V])(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	n = s.lookup(n)
+	if n == nil {
+		return nil
+	}
+	return leaf_copy(s.tree.Next(n))
+}
+
+// Prev return a copy of the previous leaf in browsing order. n could be a
+// leaf copy. Return nil if we reach end of tree or if n is no longer in the
+// tree.
+func (s *SyncTree[V])Prev(n *Leaf[V])(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	n = s.lookup(n)
+	if n == nil {
+		return nil
+	}
+	return leaf_copy(s.tree.Prev(n))
+}
+
+// IPv4LookupLonguest get a ipv4 network and return a copy of the leaf which
+// match the longest part of the prefix. Return nil if none match.
+func (s *SyncTree[V])IPv4LookupLonguest(network *net.IPNet)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.IPv4LookupLonguest(network))
+}
+
+// IPv4LookupLonguestPath take a ipv4 network, return the list of copies of
+// all leaf matching the prefix.
+func (s *SyncTree[V])IPv4LookupLonguestPath(network *net.IPNet)([]*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copies(s.tree.IPv4LookupLonguestPath(network))
+}
+
+// IPv4Get gets a ipv4 network and return a copy of the exact match of the
+// prefix.
+func (s *SyncTree[V])IPv4Get(network *net.IPNet)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.IPv4Get(network))
+}
+
+// IPv4Insert ipv4 network in the tree. Return a copy of the leaf, see Insert.
+func (s *SyncTree[V])IPv4Insert(network *net.IPNet, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.IPv4Insert(network, data)
+	return leaf_copy(n), ok
+}
+
+// IPv4DeleteNetwork lookup network and remove it. does nothing
+// if the network not exists.
+func (s *SyncTree[V])IPv4DeleteNetwork(network *net.IPNet)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.IPv4DeleteNetwork(network)
+}
+
+// IPv4Range call fn for each leaf there children match the ipv4 network.
+// See Range.
+func (s *SyncTree[V])IPv4Range(network *net.IPNet, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.IPv4NewIter(network), fn)
+}
+
+// IPv6LookupLonguest get a ipv6 network and return a copy of the leaf which
+// match the longest part of the prefix. Return nil if none match.
+func (s *SyncTree[V])IPv6LookupLonguest(network *net.IPNet)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.IPv6LookupLonguest(network))
+}
+
+// IPv6LookupLonguestPath take a ipv6 network, return the list of copies of
+// all leaf matching the prefix.
+func (s *SyncTree[V])IPv6LookupLonguestPath(network *net.IPNet)([]*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copies(s.tree.IPv6LookupLonguestPath(network))
+}
+
+// IPv6Get gets a ipv6 network and return a copy of the exact match of the
+// prefix.
+func (s *SyncTree[V])IPv6Get(network *net.IPNet)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.IPv6Get(network))
+}
+
+// IPv6Insert ipv6 network in the tree. Return a copy of the leaf, see Insert.
+func (s *SyncTree[V])IPv6Insert(network *net.IPNet, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.IPv6Insert(network, data)
+	return leaf_copy(n), ok
+}
+
+// IPv6DeleteNetwork lookup network and remove it. does nothing
+// if the network not exists.
+func (s *SyncTree[V])IPv6DeleteNetwork(network *net.IPNet)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.IPv6DeleteNetwork(network)
+}
+
+// IPv6Range call fn for each leaf there children match the ipv6 network.
+// See Range.
+func (s *SyncTree[V])IPv6Range(network *net.IPNet, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.IPv6NewIter(network), fn)
+}
+
+// AddrLookupLonguest get a IPv4 or IPv6 address and return a copy of the
+// leaf which match the longest part of the address.
+func (s *SyncTree[V])AddrLookupLonguest(addr netip.Addr)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.AddrLookupLonguest(addr))
+}
+
+// PrefixLookupLonguest get a IPv4 or IPv6 prefix and return a copy of the
+// leaf which match the longest part of the prefix.
+func (s *SyncTree[V])PrefixLookupLonguest(prefix netip.Prefix)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.PrefixLookupLonguest(prefix))
+}
+
+// PrefixLookupLonguestPath take a IPv4 or IPv6 prefix, return the list of
+// copies of all leaf matching the prefix.
+func (s *SyncTree[V])PrefixLookupLonguestPath(prefix netip.Prefix)([]*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copies(s.tree.PrefixLookupLonguestPath(prefix))
+}
+
+// PrefixGet gets a IPv4 or IPv6 prefix and return a copy of the exact match
+// of the prefix.
+func (s *SyncTree[V])PrefixGet(prefix netip.Prefix)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.PrefixGet(prefix))
+}
+
+// PrefixInsert IPv4 or IPv6 prefix in the tree. Return a copy of the leaf,
+// see Insert.
+func (s *SyncTree[V])PrefixInsert(prefix netip.Prefix, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.PrefixInsert(prefix, data)
+	return leaf_copy(n), ok
+}
+
+// PrefixDelete lookup IPv4 or IPv6 prefix and remove it. does nothing
+// if the prefix not exists.
+func (s *SyncTree[V])PrefixDelete(prefix netip.Prefix)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.PrefixDelete(prefix)
+}
+
+// PrefixRange call fn for each leaf there children match the IPv4 or IPv6
+// prefix. See Range.
+func (s *SyncTree[V])PrefixRange(prefix netip.Prefix, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.PrefixNewIter(prefix), fn)
+}
+
+// StringLookupLonguest get a string as prefix and return a copy of the leaf
+// which match the longest part of the prefix.
+func (s *SyncTree[V])StringLookupLonguest(str string)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.StringLookupLonguest(str))
+}
+
+// StringLookupLonguestPath take a string as prefix, return the list of
+// copies of all leaf matching the prefix.
+func (s *SyncTree[V])StringLookupLonguestPath(str string)([]*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copies(s.tree.StringLookupLonguestPath(str))
+}
+
+// StringGet gets a string as prefix and return a copy of the exact match of
+// the prefix.
+func (s *SyncTree[V])StringGet(str string)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.StringGet(str))
+}
+
+// StringInsert string as prefix in the tree. Return a copy of the leaf,
+// see Insert.
+func (s *SyncTree[V])StringInsert(str string, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.StringInsert(str, data)
+	return leaf_copy(n), ok
+}
+
+// StringDelete lookup string and remove it. does nothing
+// if the string not exists.
+func (s *SyncTree[V])StringDelete(str string)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.StringDelete(str)
+}
+
+// StringRange call fn for each leaf there children match the string prefix.
+// See Range.
+func (s *SyncTree[V])StringRange(str string, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.StringNewIter(str), fn)
+}
+
+// UInt64LookupGe return a copy of the greater or equal closest value of the
+// key.
+func (s *SyncTree[V])UInt64LookupGe(value uint64)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.UInt64LookupGe(value))
+}
+
+// UInt64LookupLe return a copy of the lesser or equal closest value of the
+// key.
+func (s *SyncTree[V])UInt64LookupLe(value uint64)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.UInt64LookupLe(value))
+}
+
+// UInt64Get gets a uint64 and return a copy of the exact match.
+func (s *SyncTree[V])UInt64Get(value uint64)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.UInt64Get(value))
+}
+
+// UInt64Insert uint64 in the tree. Return a copy of the leaf, see Insert.
+func (s *SyncTree[V])UInt64Insert(value uint64, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.UInt64Insert(value, data)
+	return leaf_copy(n), ok
+}
+
+// UInt64Delete lookup uint64 and remove it. does nothing
+// if the value not exists.
+func (s *SyncTree[V])UInt64Delete(value uint64)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.UInt64Delete(value)
+}
+
+// UInt64Range call fn for each leaf there children match the uint64
+// value. See Range.
+func (s *SyncTree[V])UInt64Range(value uint64, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.UInt64NewIter(value), fn)
+}
+
+// TimeLookupAfterEq return a copy of the after or equal closest value of
+// the time key.
+func (s *SyncTree[V])TimeLookupAfterEq(value time.Time)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.TimeLookupAfterEq(value))
+}
+
+// TimeLookupBeforeEq return a copy of the before or equal closest value of
+// the time key.
+func (s *SyncTree[V])TimeLookupBeforeEq(value time.Time)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.TimeLookupBeforeEq(value))
+}
+
+// TimeGet gets a time.Time and return a copy of the exact match.
+func (s *SyncTree[V])TimeGet(value time.Time)(*Leaf[V]) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return leaf_copy(s.tree.TimeGet(value))
+}
+
+// TimeInsert time.Time in the tree. Return a copy of the leaf, see Insert.
+func (s *SyncTree[V])TimeInsert(value time.Time, data V)(*Leaf[V], bool) {
+	var n *Leaf[V]
+	var ok bool
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok = s.tree.TimeInsert(value, data)
+	return leaf_copy(n), ok
+}
+
+// TimeDelete lookup time.Time and remove it. does nothing
+// if the value not exists.
+func (s *SyncTree[V])TimeDelete(value time.Time)() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tree.TimeDelete(value)
+}
+
+// TimeRange call fn for each leaf there children match the time.Time
+// value. See Range.
+func (s *SyncTree[V])TimeRange(value time.Time, fn func(n *Leaf[V])(bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.browse(s.tree.TimeNewIter(value), fn)
+}
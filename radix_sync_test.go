@@ -0,0 +1,124 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "encoding/binary"
+import "net"
+import "sync"
+import "testing"
+
+func TestSyncTree(t *testing.T) {
+	var s *SyncTree[int]
+	var wg sync.WaitGroup
+	var n *Leaf[int]
+	var nw *net.IPNet
+	var count int
+	var key []byte
+	var i int
+
+	s = NewSyncTree[int]()
+
+	/* Concurrent writers and readers */
+	for i = 0; i < 4; i++ {
+		wg.Add(2)
+		go func(base int) {
+			var k []byte
+			var j int
+
+			defer wg.Done()
+			k = make([]byte, 4)
+			for j = 0; j < 1000; j++ {
+				binary.BigEndian.PutUint32(k, uint32(base * 1000 + j))
+				s.Insert(&k, 32, j)
+				if j % 2 == 0 {
+					s.Delete(s.Get(&k, 32))
+				}
+			}
+		}(i)
+		go func() {
+			var k []byte
+			var j int
+			var l *Leaf[int]
+
+			defer wg.Done()
+			k = make([]byte, 4)
+			for j = 0; j < 1000; j++ {
+				binary.BigEndian.PutUint32(k, uint32(j))
+				s.LookupLonguest(&k, 32)
+				for l = s.First(); l != nil; l = s.Next(l) {
+				}
+				s.Range(&k, 0, func(n *Leaf[int])(bool) {
+					return true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Len() != 2000 {
+		t.Errorf("Expect 2000 entries, got %d", s.Len())
+	}
+
+	/* Returned leaves are detached copies */
+	key = make([]byte, 4)
+	binary.BigEndian.PutUint32(key, 1)
+	n = s.Get(&key, 32)
+	if n == nil || n.Data != 1 {
+		t.Fatalf("Expect leaf with data 1")
+	}
+	n.Data = 42
+	if s.Get(&key, 32).Data != 1 {
+		t.Errorf("Modifying a copy should not modify the tree")
+	}
+
+	/* Next and Delete accept copies */
+	n = s.Next(n)
+	if n == nil || n.Data != 3 {
+		t.Fatalf("Expect next leaf with data 3")
+	}
+	s.Delete(n)
+	binary.BigEndian.PutUint32(key, 3)
+	if s.Get(&key, 32) != nil || s.Len() != 1999 {
+		t.Errorf("Expect deleted leaf")
+	}
+	if s.Next(n) != nil {
+		t.Errorf("Next of deleted leaf should be nil")
+	}
+
+	/* Range stops when fn return false */
+	count = 0
+	s.Range(&key, 0, func(n *Leaf[int])(bool) {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("Expect 10 calls, got %d", count)
+	}
+
+	/* Typed wrappers */
+	_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+	s = NewSyncTree[int]()
+	s.IPv4Insert(nw, 8)
+	_, nw, _ = net.ParseCIDR("10.1.0.0/16")
+	s.IPv4Insert(nw, 16)
+	n = s.IPv4LookupLonguest(nw)
+	if n == nil || n.IPv4GetNet().String() != "10.1.0.0/16" {
+		t.Errorf("Expect 10.1.0.0/16")
+	}
+	if len(s.IPv4LookupLonguestPath(nw)) != 2 {
+		t.Errorf("Expect path of 2 entries")
+	}
+	count = 0
+	_, nw, _ = net.ParseCIDR("10.0.0.0/8")
+	s.IPv4Range(nw, func(n *Leaf[int])(bool) {
+		count += n.Data
+		return true
+	})
+	if count != 24 {
+		t.Errorf("Expect 24, got %d", count)
+	}
+	s.IPv4DeleteNetwork(nw)
+	if s.Len() != 1 {
+		t.Errorf("Expect 1 entry")
+	}
+}
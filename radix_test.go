@@ -945,3 +945,52 @@ func TestIsAlignedChildrenOf(t *testing.T) {
 		t.Errorf("Should not match")
 	}
 }
+
+func TestTreeTyped(t *testing.T) {
+	var r *Tree[uint32]
+	var n *Leaf[uint32]
+	var it *Iterator[uint32]
+	var key []byte
+	var sum uint32
+
+	r = NewTree[uint32]()
+
+	key = []byte{10, 0, 0, 0}
+	r.Insert(&key, 8, 8)
+	key = []byte{10, 1, 0, 0}
+	r.Insert(&key, 16, 16)
+	key = []byte{10, 1, 2, 0}
+	r.Insert(&key, 24, 24)
+
+	/* Typed data is returned without type assertion */
+	key = []byte{10, 1, 2, 3}
+	n = r.LookupLonguest(&key, 32)
+	if n == nil || n.Data != 24 {
+		t.Errorf("Expect leaf with data 24")
+	}
+	key = []byte{10, 1, 0, 0}
+	n = r.Get(&key, 16)
+	if n == nil || n.Data != 16 {
+		t.Errorf("Expect leaf with data 16")
+	}
+
+	key = []byte{10, 0, 0, 0}
+	for it = r.NewIter(&key, 8); it.Next(); {
+		sum += it.Get().Data
+	}
+	if sum != 48 {
+		t.Errorf("Expect sum of 48, got %d", sum)
+	}
+
+	/* Leaf size depends on data type */
+	if r.Counters().Leaf.Size >= NewRadix().Counters().Leaf.Size {
+		t.Errorf("Expect typed leaf smaller than interface leaf, got %d", r.Counters().Leaf.Size)
+	}
+
+	/* Deleting leaf reset its data */
+	n = r.Get(&key, 8)
+	r.Delete(n)
+	if n.Data != 0 || r.Len() != 2 {
+		t.Errorf("Expect deleted leaf")
+	}
+}
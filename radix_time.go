@@ -16,7 +16,7 @@ func time_to_key(value time.Time)([]byte) {
 
 // TimeGet gets a time.Time prefix and return exact match of the prefix. Exact match
 // is a node wich match the prefix bit and the length. Note the tree precision is microsecond
-func (r *Radix)TimeGet(value time.Time)(*Node) {
+func (r *Tree[V])TimeGet(value time.Time)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -29,7 +29,7 @@ func (r *Radix)TimeGet(value time.Time)(*Node) {
 // In the case of any entry match, TimeLookupAfterEq return the time
 // after or equal closest value of the time key. If the tree is
 // empty or after value not exists, return nil
-func (r *Radix)TimeLookupAfterEq(value time.Time)(*Node) {
+func (r *Tree[V])TimeLookupAfterEq(value time.Time)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -42,7 +42,7 @@ func (r *Radix)TimeLookupAfterEq(value time.Time)(*Node) {
 // In the case of any entry match, TimeLookupBeforeEq return the time
 // before or equal closest value of the time key. If the tree is
 // empty or before value not exists, return nil
-func (r *Radix)TimeLookupBeforeEq(value time.Time)(*Node) {
+func (r *Tree[V])TimeLookupBeforeEq(value time.Time)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -55,7 +55,7 @@ func (r *Radix)TimeLookupBeforeEq(value time.Time)(*Node) {
 // TimeInsert time.Time prefix in the tree. The tree accept only unique value, if
 // the prefix already exists in the tree, return existing leaf,
 // otherwise return nil. Note the tree precision is microsecond
-func (r *Radix)TimeInsert(value time.Time, data interface{})(*Node, bool) {
+func (r *Tree[V])TimeInsert(value time.Time, data V)(*Leaf[V], bool) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -67,8 +67,8 @@ func (r *Radix)TimeInsert(value time.Time, data interface{})(*Node, bool) {
 
 // TimeDelete lookup time.Time and remove it. does nothing
 // if the network not exists. Note the tree precision is microsecond
-func (r *Radix)TimeDelete(value time.Time)() {
-	var node *Node
+func (r *Tree[V])TimeDelete(value time.Time)() {
+	var node *Leaf[V]
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -86,7 +86,7 @@ func (r *Radix)TimeDelete(value time.Time)() {
 
 // TimeGetValue convert node key/length prefix to time.Time data. Note the
 // tree precision is microsecond
-func (n *Node)TimeGetValue()(time.Time) {
+func (n *Leaf[V])TimeGetValue()(time.Time) {
 	if len(n.node.Bytes) != 8 {
 		return time.Time{}
 	}
@@ -95,9 +95,27 @@ func (n *Node)TimeGetValue()(time.Time) {
 
 // UInt64NewIter return struct Iter for browsing all nodes there children
 // match the key/length prefix. Note the tree precision is microsecond
-func (r *Radix)TimeNewIter(value time.Time)(*Iter) {
+func (r *Tree[V])TimeNewIter(value time.Time)(*Iterator[V]) {
 	var key []byte
 
 	key = time_to_key(value)
 	return r.NewIter(&key, time_length)
 }
+
+// TimeNewReverseIter is like TimeNewIter, but browse the nodes from the
+// last one to the first one.
+func (r *Tree[V])TimeNewReverseIter(value time.Time)(*Iterator[V]) {
+	var key []byte
+
+	key = time_to_key(value)
+	return r.NewReverseIter(&key, time_length)
+}
+
+// TimeNewReverseIterLe return struct Iter for browsing all dates before
+// or equal than value, from the latest to the oldest.
+func (r *Tree[V])TimeNewReverseIterLe(value time.Time)(*Iterator[V]) {
+	var key []byte
+
+	key = time_to_key(value)
+	return r.new_reverse_iter_from(r.LookupLe(&key, time_length))
+}
@@ -34,3 +34,37 @@ func TestRadixTime(t *testing.T) {
 		}
 	}
 }
+
+func TestRadixTimeReverseIter(t *testing.T) {
+	var r *Tree[int]
+	var base time.Time
+	var it *Iterator[int]
+	var i int
+
+	r = NewTree[int]()
+	base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i = 0; i < 100; i++ {
+		r.TimeInsert(base.Add(time.Duration(i) * time.Minute), i)
+	}
+
+	/* Latest 10 events before 00:50:30 */
+	it = r.TimeNewReverseIterLe(base.Add(50 * time.Minute + 30 * time.Second))
+	for i = 50; i > 40 && it.Next(); i-- {
+		if it.Get().Data != i || !it.Get().TimeGetValue().Equal(base.Add(time.Duration(i) * time.Minute)) {
+			t.Fatalf("Expect event %d, got %d", i, it.Get().Data)
+		}
+	}
+	if i != 40 {
+		t.Fatalf("Reverse iteration stops at %d", i)
+	}
+
+	/* Same prefix than TimeNewIter */
+	it = r.TimeNewReverseIter(base.Add(20 * time.Minute))
+	if !it.Next() || it.Get().Data != 20 || it.Next() {
+		t.Fatalf("Expect only event 20")
+	}
+	it = r.TimeNewReverseIter(base.Add(20 * time.Minute + 30 * time.Second))
+	if it.Next() {
+		t.Fatalf("Expect no event")
+	}
+}
@@ -16,7 +16,7 @@ func uint64_to_key(value uint64)([]byte) {
 // In the case of any entry match, UInt64LookupGe return the greater or equal
 // closest value of the key. If the tree is empty or greater value not exists,
 // return nil
-func (r *Radix)UInt64LookupGe(value uint64)(*Node) {
+func (r *Tree[V])UInt64LookupGe(value uint64)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -29,7 +29,7 @@ func (r *Radix)UInt64LookupGe(value uint64)(*Node) {
 // In the case of any entry match, UInt64LookupGe return the lesser or equal
 // closest value of the key. If the tree is empty or lesser value not exists,
 // return nil
-func (r *Radix)UInt64LookupLe(value uint64)(*Node) {
+func (r *Tree[V])UInt64LookupLe(value uint64)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -41,7 +41,7 @@ func (r *Radix)UInt64LookupLe(value uint64)(*Node) {
 
 // UInt64Get gets a uint64 prefix and return exact match of the prefix. Exact match
 // is a node wich match the prefix bit and the length.
-func (r *Radix)UInt64Get(value uint64)(*Node) {
+func (r *Tree[V])UInt64Get(value uint64)(*Leaf[V]) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -54,7 +54,7 @@ func (r *Radix)UInt64Get(value uint64)(*Node) {
 // UInt64Insert uint64 prefix in the tree. The tree accept only unique value, if
 // the prefix already exists in the tree, return existing leaf,
 // otherwaise return nil.
-func (r *Radix)UInt64Insert(value uint64, data interface{})(*Node, bool) {
+func (r *Tree[V])UInt64Insert(value uint64, data V)(*Leaf[V], bool) {
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -66,8 +66,8 @@ func (r *Radix)UInt64Insert(value uint64, data interface{})(*Node, bool) {
 
 // UInt64Delete lookup uint64 and remove it. does nothing
 // if the network not exists.
-func (r *Radix)UInt64Delete(value uint64)() {
-	var node *Node
+func (r *Tree[V])UInt64Delete(value uint64)() {
+	var node *Leaf[V]
 	var key []byte
 
 	/* Get the network width. width of 0 id prohibited */
@@ -84,7 +84,7 @@ func (r *Radix)UInt64Delete(value uint64)() {
 }
 
 // UInt64GetValue convert node key/length prefix to uint64 data
-func (n *Node)UInt64GetValue()(uint64) {
+func (n *Leaf[V])UInt64GetValue()(uint64) {
 	if len(n.node.Bytes) != 8 {
 		return 0
 	}
@@ -93,9 +93,24 @@ func (n *Node)UInt64GetValue()(uint64) {
 
 // UInt64NewIter return struct Iter for browsing all nodes there children
 // match the key/length prefix.
-func (r *Radix)UInt64NewIter(value uint64)(*Iter) {
+func (r *Tree[V])UInt64NewIter(value uint64)(*Iterator[V]) {
 	var key []byte
 
 	key = uint64_to_key(value)
 	return r.NewIter(&key, length)
 }
+
+// UInt64NewReverseIter is like UInt64NewIter, but browse the nodes from
+// the last one to the first one.
+func (r *Tree[V])UInt64NewReverseIter(value uint64)(*Iterator[V]) {
+	var key []byte
+
+	key = uint64_to_key(value)
+	return r.NewReverseIter(&key, length)
+}
+
+// UInt64NewReverseIterLe return struct Iter for browsing all values lower
+// or equal than value, from the highest to the lowest.
+func (r *Tree[V])UInt64NewReverseIterLe(value uint64)(*Iterator[V]) {
+	return r.new_reverse_iter_from(r.UInt64LookupLe(value))
+}
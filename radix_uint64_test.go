@@ -2,6 +2,9 @@
 
 package radix
 
+import "math"
+import "math/rand"
+import "sort"
 import "testing"
 
 func TestRadixUInt64(t *testing.T) {
@@ -33,3 +36,90 @@ func TestRadixUInt64(t *testing.T) {
 		}
 	}
 }
+
+func TestRadixUInt64ReverseIter(t *testing.T) {
+	var values []uint64
+	var it *Iterator[uint64]
+	var rt *Tree[uint64]
+	var rnd *rand.Rand
+	var key []byte
+	var got []uint64
+	var v uint64
+	var i int
+
+	rnd = rand.New(rand.NewSource(64))
+	rt = NewTree[uint64]()
+	for i = 0; i < 1000; i++ {
+		v = rnd.Uint64() >> uint(rnd.Intn(64))
+		rt.UInt64Insert(v, v)
+	}
+
+	/* Many values under the same 56 bits prefix */
+	for i = 0; i < 10; i++ {
+		v = 0x1234567890abcd00 | uint64(rnd.Intn(256))
+		rt.UInt64Insert(v, v)
+	}
+	it = rt.NewIter(nil, 0)
+	for it.Next() {
+		values = append(values, it.Get().Data)
+	}
+	if !sort.SliceIsSorted(values, func(a int, b int)(bool) { return values[a] < values[b] }) {
+		t.Fatalf("Values are not sorted")
+	}
+
+	/* Highest values */
+	i = len(values) - 1
+	it = rt.UInt64NewReverseIterLe(math.MaxUint64)
+	for it.Next() {
+		if it.Get().Data != values[i] {
+			t.Fatalf("Expect %d, got %d", values[i], it.Get().Data)
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("Reverse iteration stops at %d", i)
+	}
+
+	/* Values lower than a middle value */
+	v = values[500] - 1
+	i = 499
+	it = rt.UInt64NewReverseIterLe(v)
+	for it.Next() {
+		if it.Get().Data != values[i] {
+			t.Fatalf("Expect %d, got %d", values[i], it.Get().Data)
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("Reverse iteration stops at %d", i)
+	}
+
+	/* The reverse iterator browse the prefix from the last leaf */
+	key = uint64_to_key(0x1234567890abcd00)
+	it = rt.NewIter(&key, 56)
+	for it.Next() {
+		got = append(got, it.Get().UInt64GetValue())
+	}
+	if len(got) < 2 {
+		t.Fatalf("Expect many values under the prefix, got %d", len(got))
+	}
+	it = rt.NewReverseIter(&key, 56)
+	for i = len(got) - 1; it.Next(); i-- {
+		if i < 0 || it.Get().UInt64GetValue() != got[i] {
+			t.Fatalf("Reverse iteration mismatch at %d", i)
+		}
+	}
+	if i != -1 {
+		t.Fatalf("Reverse iteration stops at %d", i)
+	}
+
+	/* Same prefix than UInt64NewIter */
+	it = rt.UInt64NewReverseIter(values[500])
+	if !it.Next() || it.Get().Data != values[500] || it.Next() {
+		t.Fatalf("Expect only %d", values[500])
+	}
+	it = rt.UInt64NewReverseIter(values[500] - 1)
+	if values[499] != values[500] - 1 && it.Next() {
+		t.Fatalf("Expect no value")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "errors"
+import "fmt"
+
+// ErrCorrupted is returned by Verify when the tree does not respect its
+// invariants. The returned error wraps ErrCorrupted with a description
+// of the first problem found.
+var ErrCorrupted = errors.New("radix: corrupted tree")
+
+type verify_state struct {
+	leaves int
+	nodes int
+}
+
+/* Return true if the reference points to an allocated slot */
+func (r *Tree[V])valid_ref(ref uint32)(bool) {
+	var chunk int
+	var offset int
+
+	if ref == null {
+		return false
+	}
+	chunk = int((ref >> 16) & 0x7fff)
+	offset = int(ref & 0xffff)
+	if is_leaf(ref) {
+		return chunk < len(r.leaf.pool) && offset < len(r.leaf.pool[chunk].nodes)
+	}
+	return chunk < len(r.node.pool) && offset < len(r.node.pool[chunk].nodes)
+}
+
+func (r *Tree[V])verify_node(s *verify_state, ref uint32)(error) {
+	var n *node
+	var c *node
+	var child uint32
+	var side int
+	var count int
+	var err error
+
+	n = r.r2n(ref)
+	if is_leaf(ref) {
+		s.leaves++
+	} else {
+		s.nodes++
+	}
+	if s.leaves > r.leaf.capacity || s.nodes > r.node.capacity {
+		return fmt.Errorf("%w: loop in the tree", ErrCorrupted)
+	}
+
+	/* Node content */
+	if int(n.End) >= len(n.Bytes) * 8 {
+		return fmt.Errorf("%w: node %08x end %d exceed key length", ErrCorrupted, ref, n.End)
+	}
+	if n.End < n.Start - 1 || (is_leaf(ref) && n.End < n.Start) {
+		return fmt.Errorf("%w: node %08x end %d before start %d", ErrCorrupted, ref, n.End, n.Start)
+	}
+	if !is_leaf(ref) && (n.Left == null || n.Right == null) {
+		return fmt.Errorf("%w: internal node %08x without two children", ErrCorrupted, ref)
+	}
+
+	/* Children */
+	for side, child = range []uint32{n.Left, n.Right} {
+		if child == null {
+			continue
+		}
+		if !r.valid_ref(child) {
+			return fmt.Errorf("%w: node %08x has invalid child %08x", ErrCorrupted, ref, child)
+		}
+		c = r.r2n(child)
+		if c.Parent != ref {
+			return fmt.Errorf("%w: node %08x has parent %08x, expect %08x", ErrCorrupted, child, c.Parent, ref)
+		}
+		if c.Start != n.End + 1 {
+			return fmt.Errorf("%w: node %08x start %d does not follow parent end %d", ErrCorrupted, child, c.Start, n.End)
+		}
+		if c.End < c.Start || int(c.End) >= len(c.Bytes) * 8 {
+			return fmt.Errorf("%w: node %08x end %d is not after its start %d", ErrCorrupted, child, c.End, c.Start)
+		}
+		if n.End >= 0 && !bitcmp([]byte(c.Bytes), []byte(n.Bytes), 0, n.End) {
+			return fmt.Errorf("%w: node %08x does not match parent %08x prefix", ErrCorrupted, child, ref)
+		}
+		if bitget([]byte(c.Bytes), c.Start) != byte(side) {
+			return fmt.Errorf("%w: node %08x is on the wrong side of parent %08x", ErrCorrupted, child, ref)
+		}
+		err = r.verify_node(s, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	/* Subtree count, the children counts are already checked */
+	if r.counted {
+		count = r.count(n.Left) + r.count(n.Right)
+		if is_leaf(ref) {
+			count++
+		}
+		if r.count(ref) != count {
+			return fmt.Errorf("%w: node %08x counts %d leaves, expect %d", ErrCorrupted, ref, r.count(ref), count)
+		}
+	}
+	return nil
+}
+
+/* Count free list entries, stop if the list is longer than max */
+func (r *Tree[V])verify_free(next uint32, max int)(int, error) {
+	var count int
+
+	for next != null {
+		if !r.valid_ref(next) {
+			return count, fmt.Errorf("%w: invalid reference %08x in free list", ErrCorrupted, next)
+		}
+		count++
+		if count > max {
+			return count, fmt.Errorf("%w: loop in free list", ErrCorrupted)
+		}
+		next = r.r2n(next).Left
+	}
+	return count, nil
+}
+
+// Verify walks the whole tree and check its invariants: the links between
+// parents and children, the start and end bits continuity, the children
+// bits agreement with their parent prefix and their side, the internal
+// nodes have two children, the number of leaf, the subtree counts if they
+// are enabled and the free lists. Return nil if the tree is valid,
+// otherwise return an error wrapping ErrCorrupted.
+func (r *Tree[V])Verify()(error) {
+	var s verify_state
+	var root *node
+	var free int
+	var err error
+
+	if r.Node != null {
+		if !r.valid_ref(r.Node) {
+			return fmt.Errorf("%w: invalid root reference %08x", ErrCorrupted, r.Node)
+		}
+		root = r.r2n(r.Node)
+		if root.Parent != null {
+			return fmt.Errorf("%w: root has parent %08x", ErrCorrupted, root.Parent)
+		}
+		if root.Start != 0 {
+			return fmt.Errorf("%w: root start %d is not 0", ErrCorrupted, root.Start)
+		}
+		err = r.verify_node(&s, r.Node)
+		if err != nil {
+			return err
+		}
+	}
+	if s.leaves != r.length {
+		return fmt.Errorf("%w: %d leaves found, expect %d", ErrCorrupted, s.leaves, r.length)
+	}
+
+	/* Free lists */
+	free, err = r.verify_free(r.node.next, r.node.capacity)
+	if err != nil {
+		return err
+	}
+	if free != r.node.free || s.nodes + free != r.node.capacity {
+		return fmt.Errorf("%w: %d free nodes and %d used, expect %d free and capacity %d",
+		                  ErrCorrupted, free, s.nodes, r.node.free, r.node.capacity)
+	}
+	free, err = r.verify_free(r.leaf.next, r.leaf.capacity)
+	if err != nil {
+		return err
+	}
+	if free != r.leaf.free || s.leaves + free != r.leaf.capacity {
+		return fmt.Errorf("%w: %d free leaves and %d used, expect %d free and capacity %d",
+		                  ErrCorrupted, free, s.leaves, r.leaf.free, r.leaf.capacity)
+	}
+	return nil
+}
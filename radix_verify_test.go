@@ -0,0 +1,125 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "bytes"
+import "errors"
+import "math/rand"
+import "net"
+import "testing"
+
+func verify_random_tree()(*Tree[int]) {
+	var r *Tree[int]
+	var nw *net.IPNet
+	var key []byte
+	var i int
+
+	r = NewTree[int]()
+	for i = 0; i < 20000; i++ {
+		nw = &net.IPNet{}
+		nw.IP = net.IPv4(byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)), 0)
+		nw.Mask = net.CIDRMask(1 + rand.Intn(24), 32)
+		r.IPv4Insert(nw, i)
+	}
+	for i = 0; i < 1000; i++ {
+		key = []byte(string(rune('a' + rand.Intn(26))) + string(rune('a' + rand.Intn(26))))
+		r.Insert(&key, int16(1 + rand.Intn(16)), i)
+	}
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	var r *Tree[int]
+	var l *Tree[int]
+	var buf bytes.Buffer
+	var n *Leaf[int]
+	var it *Iterator[int]
+	var p *node
+	var err error
+
+	/* Valid trees */
+	r = NewTree[int]()
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	r = verify_random_tree()
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	it = r.NewIter(nil, 0)
+	for it.Next() {
+		if rand.Intn(2) == 0 {
+			it.Delete()
+		}
+	}
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error after delete %v", err)
+	}
+	r.Compact()
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error after compact %v", err)
+	}
+
+	/* Loaded tree */
+	r.WriteTo(&buf)
+	l = NewTree[int]()
+	_, err = l.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	err = l.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error after load %v", err)
+	}
+
+	/* Length mismatch */
+	r.length++
+	err = r.Verify()
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expect ErrCorrupted, got %v", err)
+	}
+	r.length--
+
+	/* Bad parent */
+	n = r.First()
+	p = r.r2n(n.node.Parent)
+	n.node.Parent = null
+	err = r.Verify()
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expect ErrCorrupted, got %v", err)
+	}
+	n.node.Parent = r.n2r(p)
+
+	/* Swapped children */
+	p.Left, p.Right = p.Right, p.Left
+	err = r.Verify()
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expect ErrCorrupted, got %v", err)
+	}
+	p.Left, p.Right = p.Right, p.Left
+
+	/* Bad start */
+	n.node.Start++
+	err = r.Verify()
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expect ErrCorrupted, got %v", err)
+	}
+	n.node.Start--
+
+	/* Bad free count */
+	r.leaf.free++
+	err = r.Verify()
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expect ErrCorrupted, got %v", err)
+	}
+	r.leaf.free--
+
+	err = r.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+// WalkResult is returned by the Walk callbacks to drive the browsing.
+type WalkResult int
+
+const (
+	// WalkContinue browse the children of the node, then the next nodes.
+	WalkContinue WalkResult = iota
+	// WalkSkipChildren does not browse the children of the node.
+	WalkSkipChildren
+	// WalkStop ends the browsing.
+	WalkStop
+)
+
+/* Browse the subtree ref in tree order. If nodes is set, fn is also
+ * called for internal nodes with a nil leaf. Return false if the
+ * browsing is stopped.
+ */
+func (r *Tree[V])walk(ref uint32, depth int, nodes bool, fn func(key []byte, length int16, n *Leaf[V], depth int)(WalkResult))(bool) {
+	var n *node
+	var leaf *Leaf[V]
+
+	n = r.r2n(ref)
+	if is_leaf(ref) || nodes {
+		leaf = nil
+		if is_leaf(ref) {
+			leaf = n2N[V](n)
+		}
+		switch fn([]byte(n.Bytes), n.End + 1, leaf, depth) {
+		case WalkStop:
+			return false
+		case WalkSkipChildren:
+			return true
+		}
+	}
+	if n.Left != null && !r.walk(n.Left, depth + 1, nodes, fn) {
+		return false
+	}
+	if n.Right != null && !r.walk(n.Right, depth + 1, nodes, fn) {
+		return false
+	}
+	return true
+}
+
+/* Return the depth of the node, the root has depth 0 */
+func (r *Tree[V])depth(ref uint32)(int) {
+	var depth int
+
+	for {
+		ref = r.r2n(ref).Parent
+		if ref == null {
+			return depth
+		}
+		depth++
+	}
+}
+
+/* Browse the leaves and optionally the nodes matching the prefix */
+func (r *Tree[V])walk_prefix(key *[]byte, length int16, nodes bool, fn func(key []byte, length int16, n *Leaf[V], depth int)(WalkResult)) {
+	var ref uint32
+
+	if length == 0 {
+		ref = r.Node
+	} else {
+		ref = r.prefix_root(*key, length)
+	}
+	if ref == null {
+		return
+	}
+	r.walk(ref, r.depth(ref), nodes, fn)
+}
+
+// Walk browse the leaves matching the key/length prefix in tree order and
+// call fn for each one with its depth in the tree, the root has depth 0
+// and the depth counts the internal nodes. If fn returns WalkSkipChildren,
+// the more specific leaves of the leaf are not browsed, if it returns
+// WalkStop, the browsing ends. A length of 0 browse the whole tree. The
+// tree must not be modified during the walk.
+func (r *Tree[V])Walk(key *[]byte, length int16, fn func(n *Leaf[V], depth int)(WalkResult)) {
+	r.walk_prefix(key, length, false, func(key []byte, length int16, n *Leaf[V], depth int)(WalkResult) {
+		return fn(n, depth)
+	})
+}
+
+// WalkNodes is like Walk, but fn is also called for the internal nodes.
+// For an internal node, n is nil and key/length is the common prefix of
+// its children, only the length first bits of key are significant. For a
+// leaf, key/length is the prefix of the leaf. The key must not be
+// modified.
+func (r *Tree[V])WalkNodes(key *[]byte, length int16, fn func(key []byte, length int16, n *Leaf[V], depth int)(WalkResult)) {
+	r.walk_prefix(key, length, true, fn)
+}
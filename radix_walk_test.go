@@ -0,0 +1,110 @@
+// Copyright (C) 2022 Thierry Fournier <tfournier@arpalert.org>
+
+package radix
+
+import "net"
+import "testing"
+
+func TestWalk(t *testing.T) {
+	var r *Tree[string]
+	var s string
+	var key []byte
+	var list []string
+	var nodes int
+	var leaves int
+	var prev int
+
+	r = NewTree[string]()
+	for _, s = range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.2.0.0/16", "192.168.0.0/16", "192.168.1.0/24"} {
+		r.IPv4Insert(ipv4net(s), s)
+	}
+
+	/* All leaves in order */
+	list = nil
+	r.Walk(nil, 0, func(n *Leaf[string], depth int)(WalkResult) {
+		list = append(list, n.Data)
+		if depth != r.depth(r.n2r(&n.node)) {
+			t.Errorf("Bad depth %d for %s", depth, n.Data)
+		}
+		return WalkContinue
+	})
+	if len(list) != 6 || list[0] != "10.0.0.0/8" || list[5] != "192.168.1.0/24" {
+		t.Errorf("Unexpected walk %v", list)
+	}
+
+	/* Skip children */
+	list = nil
+	r.Walk(nil, 0, func(n *Leaf[string], depth int)(WalkResult) {
+		list = append(list, n.Data)
+		if n.Data == "10.1.0.0/16" || n.Data == "192.168.0.0/16" {
+			return WalkSkipChildren
+		}
+		return WalkContinue
+	})
+	if len(list) != 4 || list[1] != "10.1.0.0/16" || list[2] != "10.2.0.0/16" || list[3] != "192.168.0.0/16" {
+		t.Errorf("Unexpected walk %v", list)
+	}
+
+	/* Stop */
+	list = nil
+	r.Walk(nil, 0, func(n *Leaf[string], depth int)(WalkResult) {
+		list = append(list, n.Data)
+		if n.Data == "10.1.2.0/24" {
+			return WalkStop
+		}
+		return WalkContinue
+	})
+	if len(list) != 3 {
+		t.Errorf("Unexpected walk %v", list)
+	}
+
+	/* Prefix */
+	list = nil
+	key = []byte{10, 1, 0, 0}
+	r.Walk(&key, 16, func(n *Leaf[string], depth int)(WalkResult) {
+		list = append(list, n.Data)
+		if depth != r.depth(r.n2r(&n.node)) {
+			t.Errorf("Bad depth %d for %s", depth, n.Data)
+		}
+		return WalkContinue
+	})
+	if len(list) != 2 || list[0] != "10.1.0.0/16" || list[1] != "10.1.2.0/24" {
+		t.Errorf("Unexpected walk %v", list)
+	}
+	key = []byte{172, 16, 0, 0}
+	r.Walk(&key, 12, func(n *Leaf[string], depth int)(WalkResult) {
+		t.Errorf("Unexpected leaf %s", n.Data)
+		return WalkContinue
+	})
+
+	/* Internal nodes */
+	prev = -1
+	r.WalkNodes(nil, 0, func(key []byte, length int16, n *Leaf[string], depth int)(WalkResult) {
+		if depth > prev + 1 {
+			t.Errorf("Depth %d does not follow %d", depth, prev)
+		}
+		prev = depth
+		if n == nil {
+			nodes++
+			if length == 0 && depth != 0 {
+				t.Errorf("Unexpected empty prefix at depth %d", depth)
+			}
+		} else {
+			leaves++
+			if FormatIPv4(key, length) != n.Data {
+				t.Errorf("Expect key %s, got %s", n.Data, FormatIPv4(key, length))
+			}
+		}
+		return WalkContinue
+	})
+	if leaves != 6 || nodes != r.node.capacity - r.node.free {
+		t.Errorf("Expect 6 leaves and %d nodes, got %d and %d", r.node.capacity - r.node.free, leaves, nodes)
+	}
+}
+
+func ipv4net(s string)(*net.IPNet) {
+	var nw *net.IPNet
+
+	_, nw, _ = net.ParseCIDR(s)
+	return nw
+}